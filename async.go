@@ -0,0 +1,220 @@
+package log
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy decides what happens to a record when the async queue is
+// full
+type OverflowPolicy int
+
+const (
+	// block the caller until the queue has room
+	OVERFLOW_BLOCK OverflowPolicy = iota
+	// drop the record being logged
+	OVERFLOW_DROP_NEWEST
+	// drop the oldest queued record to make room
+	OVERFLOW_DROP_OLDEST
+	// drop the record if its level is below AsyncConfig.DropLevel, block
+	// otherwise
+	OVERFLOW_DROP_BELOW_LEVEL
+)
+
+func (policy OverflowPolicy) String() string {
+	switch policy {
+	case OVERFLOW_BLOCK:
+		return "block"
+	case OVERFLOW_DROP_NEWEST:
+		return "dropNewest"
+	case OVERFLOW_DROP_OLDEST:
+		return "dropOldest"
+	case OVERFLOW_DROP_BELOW_LEVEL:
+		return "dropBelowLevel"
+	default:
+		return "unknown"
+	}
+}
+
+type AsyncConfig struct {
+	QueueSize int            `json:"queueSize"`
+	Overflow  OverflowPolicy `json:"overflow"`
+	// used by OVERFLOW_DROP_BELOW_LEVEL
+	DropLevel LoggingLevel `json:"dropLevel"`
+}
+
+const defaultQueueSize = 1024
+
+type asyncWriter struct {
+	// dropped is accessed atomically, keep it first for 64-bit alignment
+	dropped uint64
+
+	logger *logging
+	config AsyncConfig
+	queue  chan *batch
+	done   chan struct{}
+
+	// closed is guarded by closeMux, senders hold the read lock
+	closeMux sync.RWMutex
+	closed   bool
+
+	pendingMux sync.Mutex
+	pendingCnd *sync.Cond
+	pending    int
+}
+
+func newAsyncWriter(logger *logging, config AsyncConfig) *asyncWriter {
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaultQueueSize
+	}
+
+	a := &asyncWriter{
+		logger: logger,
+		config: config,
+		queue:  make(chan *batch, config.QueueSize),
+		done:   make(chan struct{}),
+	}
+	a.pendingCnd = sync.NewCond(&a.pendingMux)
+
+	go a.run()
+
+	return a
+}
+
+func (a *asyncWriter) run() {
+	defer close(a.done)
+
+	for b := range a.queue {
+		a.logger.writeBatch(b)
+		a.finish()
+	}
+}
+
+func (a *asyncWriter) finish() {
+	a.pendingMux.Lock()
+	a.pending--
+	if a.pending == 0 {
+		a.pendingCnd.Broadcast()
+	}
+	a.pendingMux.Unlock()
+}
+
+func (a *asyncWriter) drop(b *batch) {
+	atomic.AddUint64(&a.dropped, 1)
+	a.logger.releaseBatch(b)
+}
+
+func (a *asyncWriter) enqueue(b *batch) {
+	a.closeMux.RLock()
+	defer a.closeMux.RUnlock()
+
+	// the writer is gone, fall back to a synchronous write
+	if a.closed {
+		a.logger.writeBatch(b)
+		return
+	}
+
+	a.pendingMux.Lock()
+	a.pending++
+	a.pendingMux.Unlock()
+
+	select {
+	case a.queue <- b:
+		return
+	default:
+	}
+
+	switch a.config.Overflow {
+	case OVERFLOW_DROP_NEWEST:
+		a.drop(b)
+		a.finish()
+	case OVERFLOW_DROP_OLDEST:
+		for {
+			select {
+			case a.queue <- b:
+				return
+			default:
+			}
+
+			select {
+			case old := <-a.queue:
+				a.drop(old)
+				a.finish()
+			default:
+			}
+		}
+	case OVERFLOW_DROP_BELOW_LEVEL:
+		if b.level < a.config.DropLevel {
+			a.drop(b)
+			a.finish()
+			return
+		}
+
+		a.queue <- b
+	default:
+		a.queue <- b
+	}
+}
+
+// flush blocks until every queued record is written or dropped
+func (a *asyncWriter) flush() {
+	a.pendingMux.Lock()
+	for a.pending > 0 {
+		a.pendingCnd.Wait()
+	}
+	a.pendingMux.Unlock()
+}
+
+// close drains the queue and stops the writer
+func (a *asyncWriter) close() {
+	a.closeMux.Lock()
+	if a.closed {
+		a.closeMux.Unlock()
+		return
+	}
+	a.closed = true
+	close(a.queue)
+	a.closeMux.Unlock()
+
+	<-a.done
+}
+
+// EnableAsync makes the records be written by a background goroutine
+// through a bounded queue, the caller only formats the record
+func (l *logging) EnableAsync(config AsyncConfig) {
+	l.mux.Lock()
+	old := l.async
+	l.async = newAsyncWriter(l, config)
+	l.mux.Unlock()
+
+	if old != nil {
+		old.close()
+	}
+}
+
+// Dropped returns the number of records dropped by the async queue
+func (l *logging) Dropped() uint64 {
+	l.mux.Lock()
+	async := l.async
+	l.mux.Unlock()
+
+	if async == nil {
+		return 0
+	}
+
+	return atomic.LoadUint64(&async.dropped)
+}
+
+// Flush writes the summaries of the pending repetitions, see EnableDedup,
+// and blocks until every record queued in async mode is written
+func (l *logging) Flush() {
+	l.flushDedup()
+
+	l.mux.Lock()
+	async := l.async
+	l.mux.Unlock()
+
+	if async != nil {
+		async.flush()
+	}
+}
@@ -0,0 +1,130 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// blockingOutput blocks every write until release is closed
+type blockingOutput struct {
+	mux     sync.Mutex
+	buf     bytes.Buffer
+	entered chan struct{}
+	release chan struct{}
+}
+
+func newBlockingOutput() *blockingOutput {
+	return &blockingOutput{
+		entered: make(chan struct{}, 100),
+		release: make(chan struct{}),
+	}
+}
+
+func (b *blockingOutput) Write(p []byte) (int, error) {
+	b.entered <- struct{}{}
+	<-b.release
+
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *blockingOutput) String() string {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.buf.String()
+}
+
+func TestAsyncFlush(t *testing.T) {
+	output := &BufferOutput{}
+
+	logging := NewLogging("test", INFO_LEVEL, 4)
+	logging.SetOutPut(output)
+	logging.EnableAsync(AsyncConfig{QueueSize: 16})
+	defer logging.Close()
+
+	for i := 0; i < 100; i++ {
+		logging.Infof("message %d", i)
+	}
+	logging.Flush()
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 100 {
+		t.Errorf("expected 100 lines, got %d", len(lines))
+		return
+	}
+
+	if !strings.HasSuffix(lines[0], "message 0") || !strings.HasSuffix(lines[99], "message 99") {
+		t.Error("async records are out of order")
+	}
+
+	if logging.Dropped() != 0 {
+		t.Errorf("unexpected dropped records %d", logging.Dropped())
+	}
+}
+
+func testOverflow(t *testing.T, config AsyncConfig, levels []LoggingLevel, expected []string, dropped uint64) {
+	output := newBlockingOutput()
+
+	logging := NewLogging("test", TRACE_LEVEL, 4)
+	logging.SetOutPut(output)
+	logging.EnableAsync(config)
+
+	// the first record is held by the writer, the others fill the queue
+	logging.Info("first")
+	<-output.entered
+
+	for i, level := range levels {
+		logging.printf(level, "message %d", i)
+	}
+
+	close(output.release)
+	logging.Close()
+
+	if logging.Dropped() != dropped {
+		t.Errorf("expected %d dropped records, got %d", dropped, logging.Dropped())
+	}
+
+	s := output.String()
+	for _, message := range expected {
+		if !strings.Contains(s, message+"\n") {
+			t.Errorf("expected %q in %q", message, s)
+		}
+	}
+}
+
+func TestAsyncDropNewest(t *testing.T) {
+	testOverflow(t, AsyncConfig{QueueSize: 2, Overflow: OVERFLOW_DROP_NEWEST},
+		[]LoggingLevel{INFO_LEVEL, INFO_LEVEL, INFO_LEVEL},
+		[]string{"first", "message 0", "message 1"}, 1)
+}
+
+func TestAsyncDropOldest(t *testing.T) {
+	testOverflow(t, AsyncConfig{QueueSize: 2, Overflow: OVERFLOW_DROP_OLDEST},
+		[]LoggingLevel{INFO_LEVEL, INFO_LEVEL, INFO_LEVEL},
+		[]string{"first", "message 1", "message 2"}, 1)
+}
+
+func TestAsyncDropBelowLevel(t *testing.T) {
+	testOverflow(t, AsyncConfig{QueueSize: 2, Overflow: OVERFLOW_DROP_BELOW_LEVEL, DropLevel: WARN_LEVEL},
+		[]LoggingLevel{INFO_LEVEL, INFO_LEVEL, DEBUG_LEVEL, INFO_LEVEL},
+		[]string{"first", "message 0", "message 1"}, 2)
+}
+
+func TestAsyncClose(t *testing.T) {
+	output := &BufferOutput{}
+
+	logging := NewLogging("test", INFO_LEVEL, 4)
+	logging.SetOutPut(output)
+	logging.EnableAsync(AsyncConfig{})
+
+	logging.Info("before close")
+	logging.Close()
+	logging.Info("after close")
+
+	if !strings.Contains(output.String(), "before close") || !strings.Contains(output.String(), "after close") {
+		t.Errorf("unexpected output %q", output.String())
+	}
+}
@@ -9,6 +9,9 @@ func TestAlloc(t *testing.T) {
 	pool := NewBufferPool()
 
 	var m1, m2 runtime.MemStats
+	// collect the garbage of the previous tests first, otherwise it is
+	// counted as frees of the pool
+	runtime.GC()
 	runtime.ReadMemStats(&m1)
 	runtime.GC()
 
@@ -0,0 +1,310 @@
+package log
+
+import (
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// packagePath is the import path of this package
+var packagePath = reflect.TypeOf(LogRecord{}).PkgPath()
+
+// CallerFormat decides how the path of the caller is printed
+type CallerFormat int
+
+const (
+	// the absolute path given by runtime.Caller
+	CALLER_FULL CallerFormat = iota
+	// the path relative to the root of the main module, e.g.
+	// "cmd/server/main.go", so the build paths do not leak. The files of the
+	// other modules keep the import path of their package, e.g.
+	// "github.com/wh8199/log/output.go"
+	CALLER_TRIMMED
+	// the last CallerOptions.Segments segments of the path
+	CALLER_SHORT
+)
+
+type CallerOptions struct {
+	Format CallerFormat `json:"format"`
+	// used by CALLER_SHORT, 2 if it is not set
+	Segments int `json:"segments"`
+	// print the package qualified name of the function, e.g.
+	// "github.com/wh8199/log.(*logging).Info"
+	Function bool `json:"function"`
+}
+
+// SetCallerOptions sets how the caller of the records is printed
+func (l *logging) SetCallerOptions(opts CallerOptions) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	l.callerOptions = opts
+}
+
+// EnableCaller turns the caller lookup on or off, without it the records
+// have no caller and runtime.Caller is not called
+func (l *logging) EnableCaller(enable bool) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	l.enableCaller = enable
+}
+
+// needFunction reports whether the function of the caller must be resolved
+func (o *CallerOptions) needFunction() bool {
+	return o.Function || o.Format == CALLER_TRIMMED
+}
+
+// mainModule is the path of the main module and mainPackage the import path
+// of the main package, they are empty when they are not known, e.g. when the
+// program is built from a list of files
+var mainModule, mainPackage = readBuildInfo()
+
+func readBuildInfo() (string, string) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "", ""
+	}
+
+	// the main package of a test binary is generated
+	if info.Path == "command-line-arguments" || strings.HasSuffix(info.Path, ".test") {
+		return info.Main.Path, ""
+	}
+
+	return info.Main.Path, info.Path
+}
+
+// trimCaller returns file relative to the root of module when function
+// belongs to it, otherwise the import path of the package of function
+// followed by the file name
+func trimCaller(file, function, module, mainPackage string) string {
+	pkg := funcPackage(function)
+	switch {
+	case pkg == "":
+		return file
+	case pkg == "main":
+		if mainPackage == "" {
+			// the directory of the main package is not known
+			return shortCaller(file, 1)
+		}
+		pkg = mainPackage
+	case strings.HasSuffix(pkg, "_test"):
+		// an external test package is in the directory of the package it
+		// tests
+		pkg = strings.TrimSuffix(pkg, "_test")
+	}
+
+	name := shortCaller(file, 1)
+	switch {
+	case module == "":
+	case pkg == module:
+		return name
+	case strings.HasPrefix(pkg, module+"/"):
+		return pkg[len(module)+1:] + "/" + name
+	}
+
+	return pkg + "/" + name
+}
+
+// funcPackage returns the import path of the package of function, e.g.
+// "github.com/wh8199/log" for "github.com/wh8199/log.(*logging).Info"
+func funcPackage(function string) string {
+	slash := strings.LastIndexByte(function, '/')
+	if slash < 0 {
+		slash = 0
+	}
+
+	dot := strings.IndexByte(function[slash:], '.')
+	if dot < 0 {
+		return ""
+	}
+
+	// the dots of the last path element are escaped in the symbol names
+	return strings.Replace(function[:slash+dot], "%2e", ".", -1)
+}
+
+// funcName returns the name of the function at pc
+func funcName(pc uintptr) string {
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return ""
+	}
+
+	return fn.Name()
+}
+
+// apply rewrites the caller of record as described by the options
+func (o *CallerOptions) apply(record *LogRecord) {
+	switch o.Format {
+	case CALLER_TRIMMED:
+		record.file = trimCaller(record.file, record.function, mainModule, mainPackage)
+	case CALLER_SHORT:
+		segments := o.Segments
+		if segments <= 0 {
+			segments = defaultCallerSegments
+		}
+		record.file = shortCaller(record.file, segments)
+	}
+
+	if !o.Function {
+		record.function = ""
+	}
+}
+
+// helpers holds the packages and the functions whose frames are skipped
+// when the caller is looked up, it is replaced as a whole on every change
+type helpers struct {
+	packages  map[string]struct{}
+	functions map[string]struct{}
+}
+
+var (
+	helpersMux   sync.Mutex
+	helpersValue atomic.Value
+)
+
+func init() {
+	helpersValue.Store(&helpers{
+		// this package and the stdlib log package, see NewStdLogger
+		packages:  map[string]struct{}{packagePath: {}, "log": {}},
+		functions: map[string]struct{}{},
+	})
+}
+
+func updateHelpers(update func(h *helpers)) {
+	helpersMux.Lock()
+	defer helpersMux.Unlock()
+
+	old := helpersValue.Load().(*helpers)
+	h := &helpers{
+		packages:  make(map[string]struct{}, len(old.packages)+1),
+		functions: make(map[string]struct{}, len(old.functions)+1),
+	}
+	for pkg := range old.packages {
+		h.packages[pkg] = struct{}{}
+	}
+	for function := range old.functions {
+		h.functions[function] = struct{}{}
+	}
+
+	update(h)
+	helpersValue.Store(h)
+}
+
+// RegisterHelperPackage makes the caller lookup skip the frames of the
+// package with the import path pkg, e.g. the package wrapping this logger
+func RegisterHelperPackage(pkg string) {
+	updateHelpers(func(h *helpers) {
+		h.packages[pkg] = struct{}{}
+	})
+}
+
+// RegisterHelperFunc makes the caller lookup skip the frames of the
+// package qualified function name, e.g. "github.com/org/app/db.logQuery",
+// and of the closures it contains
+func RegisterHelperFunc(name string) {
+	updateHelpers(func(h *helpers) {
+		h.functions[name] = struct{}{}
+	})
+}
+
+// Helper marks the function calling it as a logging helper, like
+// testing.T.Helper, so the caller lookup reports the caller of that function
+func Helper() {
+	pc, _, _, ok := runtime.Caller(1)
+	if !ok {
+		return
+	}
+
+	name := funcName(pc)
+	if _, ok := helpersValue.Load().(*helpers).functions[name]; ok {
+		return
+	}
+
+	RegisterHelperFunc(name)
+}
+
+// isHelper reports whether frame belongs to a registered helper package,
+// this package included, or to a registered helper function
+func (h *helpers) isHelper(frame *runtime.Frame) bool {
+	if _, ok := h.packages[funcPackage(frame.Function)]; ok {
+		return true
+	}
+
+	if len(h.functions) == 0 {
+		return false
+	}
+
+	// the closures are named like function.func1
+	name := frame.Function
+	for {
+		if _, ok := h.functions[name]; ok {
+			return true
+		}
+
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 || !strings.HasPrefix(name[i:], ".func") {
+			return false
+		}
+		name = name[:i]
+	}
+}
+
+// callerFrame returns the first frame of the stack which is not in this
+// package nor in a logging helper
+func callerFrame() (runtime.Frame, bool) {
+	var pcs [32]uintptr
+	n := runtime.Callers(2, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	h := helpersValue.Load().(*helpers)
+
+	for {
+		frame, more := frames.Next()
+		if !h.isHelper(&frame) {
+			return frame, true
+		}
+
+		if !more {
+			return frame, n != 0
+		}
+	}
+}
+
+// resolveCaller looks up the caller of record unless it is already known.
+// The stack is walked up to the first frame outside of the logging helpers,
+// unless the depth of the caller is set explicitly by Caller or CallerLevel,
+// then skip is counted from the caller of resolveCaller
+func resolveCaller(record *LogRecord, opts *CallerOptions, skip int) {
+	if record.callerResolved {
+		opts.apply(record)
+		return
+	}
+
+	if record.explicitCaller {
+		pc, file, line, ok := runtime.Caller(skip + 1)
+		if !ok {
+			return
+		}
+
+		record.file = file
+		record.line = line
+		if opts.needFunction() {
+			record.function = funcName(pc)
+		}
+	} else {
+		frame, ok := callerFrame()
+		if !ok {
+			return
+		}
+
+		record.file = frame.File
+		record.line = frame.Line
+		record.function = frame.Function
+	}
+	record.callerResolved = true
+
+	opts.apply(record)
+}
@@ -0,0 +1,66 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDisableCaller(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logging := NewLogging("test", INFO_LEVEL, 4)
+	logging.SetOutPut(buf)
+	logging.SetFormatter(LogfmtFormatter)
+	record := logging.With("id", 1)
+
+	logging.EnableCaller(false)
+	logging.Info("hello")
+	record.Info("hello")
+
+	if strings.Contains(buf.String(), "caller") {
+		t.Errorf("unexpected caller in %q", buf.String())
+	}
+}
+
+func TestFuncPackage(t *testing.T) {
+	cases := []struct {
+		function string
+		expected string
+	}{
+		{"github.com/wh8199/log.(*logging).Info", "github.com/wh8199/log"},
+		{"github.com/wh8199/log.TestFuncPackage.func1", "github.com/wh8199/log"},
+		{"main.main", "main"},
+		{"gopkg.in/yaml%2ev3.Unmarshal", "gopkg.in/yaml.v3"},
+		{"", ""},
+	}
+
+	for _, c := range cases {
+		if pkg := funcPackage(c.function); pkg != c.expected {
+			t.Errorf("expected %q for %q, got %q", c.expected, c.function, pkg)
+		}
+	}
+}
+
+func TestTrimCaller(t *testing.T) {
+	cases := []struct {
+		file        string
+		function    string
+		mainPackage string
+		expected    string
+	}{
+		{"/src/app/cmd/server/main.go", "main.main", "github.com/org/app/cmd/server", "cmd/server/main.go"},
+		{"/src/app/main.go", "main.main", "github.com/org/app", "main.go"},
+		{"/src/app/cmd/server/main.go", "main.main", "", "main.go"},
+		{"/src/app/db/db.go", "github.com/org/app/db.(*DB).Query", "", "db/db.go"},
+		{"/src/app/db/db_test.go", "github.com/org/app/db_test.TestQuery", "", "db/db_test.go"},
+		{"/go/pkg/mod/github.com/wh8199/log@v1.0.0/output.go", "github.com/wh8199/log.(*logging).Info", "", "github.com/wh8199/log/output.go"},
+		{"/src/app/db/db.go", "", "", "/src/app/db/db.go"},
+	}
+
+	for _, c := range cases {
+		if file := trimCaller(c.file, c.function, "github.com/org/app", c.mainPackage); file != c.expected {
+			t.Errorf("expected %q for %s, got %q", c.expected, c.function, file)
+		}
+	}
+}
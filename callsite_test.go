@@ -0,0 +1,149 @@
+package log_test
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/wh8199/log"
+)
+
+// The frames of the log package are skipped when the caller is looked up,
+// its own tests included, so the call sites are tested from this package
+// like any other caller.
+
+const packagePath = "github.com/wh8199/log"
+
+func TestCallerOptions(t *testing.T) {
+	cases := []struct {
+		opts     log.CallerOptions
+		expected string
+	}{
+		// relative to the root of the module
+		{log.CallerOptions{Format: log.CALLER_TRIMMED}, " callsite_test.go:"},
+		{log.CallerOptions{Format: log.CALLER_SHORT, Segments: 1}, " callsite_test.go:"},
+		{log.CallerOptions{Format: log.CALLER_SHORT, Function: true}, " " + packagePath + "_test.TestCallerOptions "},
+	}
+
+	for _, c := range cases {
+		buf := &bytes.Buffer{}
+
+		logging := log.NewLogging("test", log.INFO_LEVEL, 4)
+		logging.SetOutPut(buf)
+		logging.SetCallerOptions(c.opts)
+		logging.Info("hello")
+
+		if !strings.Contains(buf.String(), c.expected) {
+			t.Errorf("expected %q in %q", c.expected, buf.String())
+		}
+	}
+}
+
+func TestFormatterCaller(t *testing.T) {
+	pattern, err := log.PatternFormatter("%level %file{1}:%line %msg")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	formatters := []log.Formatter{
+		log.DefaultFormater,
+		log.JSONFormatter(log.JSONFormatterOptions{}),
+		log.LogfmtFormatter,
+		log.ConsoleFormatter(log.ConsoleFormatterOptions{Color: log.COLOR_NEVER}),
+		pattern,
+	}
+
+	for i, formatter := range formatters {
+		buf := &bytes.Buffer{}
+
+		logging := log.NewLogging("test", log.INFO_LEVEL, 4)
+		logging.SetOutPut(buf)
+		logging.SetFormatter(formatter)
+
+		_, _, line, _ := runtime.Caller(0)
+		logging.Module("db").Info("hello")
+
+		expected := "callsite_test.go:" + strconv.Itoa(line+1)
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("formatter %d: expected %q in %q", i, expected, buf.String())
+		}
+	}
+}
+
+// logger is the part of the logging used by the helpers
+type logger interface {
+	Info(args ...interface{})
+	Warn(args ...interface{})
+}
+
+func logThroughHelper(l logger, message string) {
+	log.Helper()
+	l.Info(message)
+}
+
+func logThroughRegisteredHelper(l logger, message string) {
+	func() {
+		l.Warn(message)
+	}()
+}
+
+func TestCallerFrameSkipping(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	// the caller level does not matter anymore
+	logging := log.NewLogging("test", log.INFO_LEVEL, 42)
+	logging.SetOutPut(buf)
+	logging.SetCallerOptions(log.CallerOptions{Format: log.CALLER_SHORT, Segments: 1})
+	log.RegisterHelperFunc(packagePath + "_test.logThroughRegisteredHelper")
+
+	_, _, line, _ := runtime.Caller(0)
+	logging.Info("direct")
+	logging.Module("db").With("id", 1).Info("record")
+	logThroughHelper(logging, "helper")
+	logThroughRegisteredHelper(logging, "registered")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Errorf("expected 4 lines, got %q", lines)
+		return
+	}
+
+	for i, l := range lines {
+		expected := " callsite_test.go:" + strconv.Itoa(line+1+i) + " "
+		if !strings.Contains(l, expected) {
+			t.Errorf("expected %q in %q", expected, l)
+		}
+	}
+}
+
+type callerHook struct {
+	file string
+	line int
+}
+
+func (h *callerHook) Levels() []log.LoggingLevel {
+	return log.AllLevels
+}
+
+func (h *callerHook) Fire(entry *log.Entry) error {
+	h.file, h.line = entry.Caller()
+	return nil
+}
+
+func TestHookCaller(t *testing.T) {
+	hook := &callerHook{}
+
+	logging := log.NewLogging("test", log.INFO_LEVEL, 4)
+	logging.SetOutPut(&bytes.Buffer{})
+	logging.AddHook(hook, log.HOOK_BEFORE_WRITE)
+
+	_, file, line, _ := runtime.Caller(0)
+	logging.Error("failed")
+
+	if hook.file != file || hook.line != line+1 {
+		t.Errorf("unexpected caller %s:%d", hook.file, hook.line)
+	}
+}
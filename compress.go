@@ -0,0 +1,168 @@
+package log
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Codec compresses rotated log files
+type Codec interface {
+	// Extension is appended to the name of the compressed file, e.g. ".gz"
+	Extension() string
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+}
+
+var (
+	codecMux sync.RWMutex
+	codecs   = map[string]Codec{
+		"gzip": gzipCodec{},
+	}
+)
+
+// RegisterCodec makes a codec available to LogRotateConfig.Compress
+func RegisterCodec(name string, codec Codec) {
+	codecMux.Lock()
+	defer codecMux.Unlock()
+
+	codecs[name] = codec
+}
+
+func getCodec(name string) (Codec, bool) {
+	codecMux.RLock()
+	defer codecMux.RUnlock()
+
+	codec, ok := codecs[name]
+	return codec, ok
+}
+
+func codecExtensions() []string {
+	codecMux.RLock()
+	defer codecMux.RUnlock()
+
+	extensions := make([]string, 0, len(codecs))
+	for _, codec := range codecs {
+		extensions = append(extensions, codec.Extension())
+	}
+
+	return extensions
+}
+
+type gzipCodec struct{}
+
+func (gzipCodec) Extension() string {
+	return ".gz"
+}
+
+func (gzipCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+// compressFile compresses fileName into fileName+codec.Extension() and
+// removes fileName, the original file is kept if anything fails
+func compressFile(fileName string, codec Codec) (err error) {
+	src, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dstName := fileName + codec.Extension()
+	dst, err := os.OpenFile(dstName, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			dst.Close()
+			os.Remove(dstName)
+		}
+	}()
+
+	w, err := codec.NewWriter(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(w, src); err != nil {
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	if err := dst.Close(); err != nil {
+		return err
+	}
+
+	src.Close()
+	return os.Remove(fileName)
+}
+
+// compressInBackground compresses a rotated file without blocking the
+// writes to the new file, a failure is returned by the next Rotate
+func (f *FileOutput) compressInBackground(fileName string) {
+	codec, ok := getCodec(f.Compress)
+	if !ok {
+		f.setCompressError(fmt.Errorf("unknown compress codec %q", f.Compress))
+		return
+	}
+
+	// the retention leaves the file and its compressed copy alone until the
+	// compression is done
+	dstName := fileName + codec.Extension()
+
+	f.compressMux.Lock()
+	if f.compressing == nil {
+		f.compressing = map[string]bool{}
+	}
+	f.compressing[fileName] = true
+	f.compressing[dstName] = true
+	f.compressMux.Unlock()
+
+	f.compressWG.Add(1)
+	go func() {
+		defer f.compressWG.Done()
+
+		err := compressFile(fileName, codec)
+
+		f.compressMux.Lock()
+		delete(f.compressing, fileName)
+		delete(f.compressing, dstName)
+		f.compressMux.Unlock()
+
+		if err != nil {
+			f.setCompressError(fmt.Errorf("compress %s: %v", fileName, err))
+		}
+	}()
+}
+
+// isCompressing reports whether path is a file being compressed or its
+// compressed copy
+func (f *FileOutput) isCompressing(path string) bool {
+	f.compressMux.Lock()
+	defer f.compressMux.Unlock()
+
+	return f.compressing[path]
+}
+
+func (f *FileOutput) setCompressError(err error) {
+	f.compressMux.Lock()
+	defer f.compressMux.Unlock()
+
+	f.compressErr = err
+}
+
+// compressError returns the last compression failure once
+func (f *FileOutput) compressError() error {
+	f.compressMux.Lock()
+	defer f.compressMux.Unlock()
+
+	err := f.compressErr
+	f.compressErr = nil
+	return err
+}
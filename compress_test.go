@@ -0,0 +1,225 @@
+package log
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompressRotatedLog(t *testing.T) {
+	fileOutput := FileOutput{
+		LogRotateConfig: LogRotateConfig{
+			EnableLogFile: true,
+			Prefix:        "log",
+			FileDir:       ".",
+			MaxSize:       1,
+			MaxLogLife:    60,
+			Compress:      "gzip",
+		},
+	}
+	defer cleanLogs()
+	defer cleanCompressedLogs()
+
+	currentTime := time.Now()
+	oldTime := currentTime.Add(-time.Second * 10)
+	if err := fileOutput.generateFileWithTime(oldTime); err != nil {
+		t.Error(err)
+		return
+	}
+
+	oldFileName := fileOutput.fileName
+	if _, err := fileOutput.Write([]byte("Test Message")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := fileOutput.generateFileWithTime(currentTime); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := fileOutput.Close(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if isExist(oldFileName) {
+		t.Error("rotated log is not removed after compressing")
+		return
+	}
+
+	file, err := os.Open(oldFileName + ".gz")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer file.Close()
+
+	r, err := gzip.NewReader(file)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if string(data) != "Test Message" {
+		t.Errorf("unexpected compressed content %q", data)
+		return
+	}
+
+	logs, err := fileOutput.getAllLogs()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if ts, ok := logs[oldFileName+".gz"]; !ok || ts != oldTime.Unix() {
+		t.Errorf("compressed log is not recognised: %v", logs)
+	}
+}
+
+func TestParseCompressedFileName(t *testing.T) {
+	fileOutput := FileOutput{
+		LogRotateConfig: LogRotateConfig{
+			Prefix: "log",
+		},
+	}
+
+	currentTime := time.Now()
+	ok, ts, err := fileOutput.parseFileName(generateFileName(fileOutput.Prefix, currentTime) + ".gz")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if !ok || ts != currentTime.Unix() {
+		t.Error("parse compressed log file name failed")
+	}
+
+	if ok, _, _ := fileOutput.parseFileName("log.gz"); ok {
+		t.Error("test parsing compressed log name failed")
+	}
+}
+
+// blockingCodec blocks the compression until release is closed, or fails it
+// with err
+type blockingCodec struct {
+	release chan struct{}
+	err     error
+}
+
+func (c blockingCodec) Extension() string {
+	return ".blk"
+}
+
+func (c blockingCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	if c.release != nil {
+		<-c.release
+	}
+
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	return gzip.NewWriter(w), nil
+}
+
+func TestCompressPendingRetention(t *testing.T) {
+	release := make(chan struct{})
+	RegisterCodec("blocking", blockingCodec{release: release})
+
+	fileOutput := FileOutput{
+		LogRotateConfig: LogRotateConfig{
+			EnableLogFile: true,
+			Prefix:        "log",
+			FileDir:       ".",
+			MaxSize:       1,
+			Compress:      "blocking",
+		},
+	}
+	defer cleanLogs()
+	defer cleanCompressedLogs()
+
+	currentTime := time.Now()
+	if err := fileOutput.generateFileWithTime(currentTime.Add(-10 * time.Second)); err != nil {
+		t.Error(err)
+		return
+	}
+
+	oldFileName := fileOutput.fileName
+	if err := fileOutput.generateFileWithTime(currentTime); err != nil {
+		t.Error(err)
+		return
+	}
+
+	logs, err := fileOutput.getAllLogs()
+	close(release)
+	fileOutput.Close()
+	os.Remove(oldFileName + ".blk")
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// the file being compressed is not a backup yet
+	if _, ok := logs[oldFileName]; ok || len(logs) != 1 {
+		t.Errorf("unexpected logs %v", logs)
+	}
+}
+
+func TestCompressError(t *testing.T) {
+	RegisterCodec("failing", blockingCodec{err: errors.New("no space left")})
+
+	cases := []struct {
+		codec    string
+		expected string
+	}{
+		{"failing", "compress "},
+		{"unknown", `unknown compress codec "unknown"`},
+	}
+
+	for _, c := range cases {
+		fileOutput := FileOutput{
+			LogRotateConfig: LogRotateConfig{
+				EnableLogFile: true,
+				Prefix:        "log",
+				FileDir:       ".",
+				MaxSize:       1,
+				Compress:      c.codec,
+			},
+		}
+
+		currentTime := time.Now()
+		if err := fileOutput.generateFileWithTime(currentTime.Add(-10 * time.Second)); err != nil {
+			t.Error(err)
+			return
+		}
+
+		if err := fileOutput.generateFileWithTime(currentTime); err != nil {
+			t.Error(err)
+			return
+		}
+		fileOutput.compressWG.Wait()
+
+		// the failure is returned by the next rotation, so it reaches the
+		// error handler of the logging
+		err := fileOutput.Rotate()
+		fileOutput.Close()
+		cleanLogs()
+
+		if err == nil || !strings.HasPrefix(err.Error(), c.expected) {
+			t.Errorf("expected %q, got %v", c.expected, err)
+		}
+	}
+}
@@ -0,0 +1,792 @@
+package log
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config describes the global logger and the named loggers, it is read from
+// a JSON or YAML file by LoadConfig
+type Config struct {
+	// the logger used by the package level functions
+	Global *LoggerConfig `json:"global"`
+	// the named loggers, they are created if they are not registered yet
+	// and registered under their name
+	Loggers map[string]*LoggerConfig `json:"loggers"`
+}
+
+type LoggerConfig struct {
+	// "info" if it is not set
+	Level     string           `json:"level"`
+	Formatter *FormatterConfig `json:"formatter"`
+	// module pattern to level, e.g. "db.*": "debug"
+	Modules map[string]string `json:"modules"`
+	// the records at or above this level carry a stack trace, no stack is
+	// captured when it is not set
+	Stack string `json:"stack"`
+	// the records are written to stdout when there is no sink
+	Sinks []*SinkConfig `json:"sinks"`
+}
+
+type FormatterConfig struct {
+	// "text", "json", "logfmt" or "pattern"
+	Type string               `json:"type"`
+	JSON JSONFormatterOptions `json:"json"`
+	// the pattern of PatternFormatter
+	Pattern string `json:"pattern"`
+}
+
+type SinkConfig struct {
+	// "console", "file", "syslog" or "network"
+	Type string `json:"type"`
+	// the sink receives every record when it is not set
+	Level string `json:"level"`
+	// the formatter of the logger is used when it is not set
+	Formatter *FormatterConfig `json:"formatter"`
+	// "stdout" or "stderr", used by console
+	Stream  string             `json:"stream"`
+	File    *LogRotateConfig   `json:"file"`
+	Syslog  *SyslogSinkConfig  `json:"syslog"`
+	Network *NetworkSinkConfig `json:"network"`
+	// collapse the identical records written to the sink
+	Dedup *DedupSinkConfig `json:"dedup"`
+}
+
+// DedupSinkConfig is the file form of DedupConfig, the interval is a string
+// such as "30s"
+type DedupSinkConfig struct {
+	Interval string `json:"interval"`
+	Windowed bool   `json:"windowed"`
+}
+
+// SyslogSinkConfig is the file form of SyslogConfig
+type SyslogSinkConfig struct {
+	Network string `json:"network"`
+	Address string `json:"address"`
+	// e.g. "user", "daemon" or "local0"
+	Facility string `json:"facility"`
+	AppName  string `json:"appName"`
+	Hostname string `json:"hostname"`
+	// "rfc5424" or "rfc3164"
+	Format string `json:"format"`
+	// use TLS over tcp, the system roots are used unless CAFile is set
+	TLS    bool   `json:"tls"`
+	CAFile string `json:"caFile"`
+}
+
+// NetworkSinkConfig is the file form of NetworkConfig, the durations are
+// strings such as "5s"
+type NetworkSinkConfig struct {
+	Network string `json:"network"`
+	Address string `json:"address"`
+	// "newline" or "length"
+	Framing      string `json:"framing"`
+	BufferSize   int    `json:"bufferSize"`
+	DialTimeout  string `json:"dialTimeout"`
+	WriteTimeout string `json:"writeTimeout"`
+	MinBackoff   string `json:"minBackoff"`
+	MaxBackoff   string `json:"maxBackoff"`
+}
+
+const globalLoggerName = "global"
+
+var syslogFacilities = map[string]int{
+	"kern":     LOG_KERN,
+	"user":     LOG_USER,
+	"mail":     LOG_MAIL,
+	"daemon":   LOG_DAEMON,
+	"auth":     LOG_AUTH,
+	"syslog":   LOG_SYSLOG,
+	"lpr":      LOG_LPR,
+	"news":     LOG_NEWS,
+	"uucp":     LOG_UUCP,
+	"cron":     LOG_CRON,
+	"authpriv": LOG_AUTHPRIV,
+	"ftp":      LOG_FTP,
+	"local0":   LOG_LOCAL0,
+	"local1":   LOG_LOCAL1,
+	"local2":   LOG_LOCAL2,
+	"local3":   LOG_LOCAL3,
+	"local4":   LOG_LOCAL4,
+	"local5":   LOG_LOCAL5,
+	"local6":   LOG_LOCAL6,
+	"local7":   LOG_LOCAL7,
+}
+
+// LoadConfig reads the config file at path, validates it and applies it,
+// the format is chosen by the extension: ".json", ".yaml" or ".yml"
+func LoadConfig(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	config, err := ParseConfig(data, strings.TrimPrefix(filepath.Ext(path), "."))
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	if err := ApplyConfig(config); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	return nil
+}
+
+// ParseConfig decodes a config, format is "json", "yaml" or "yml". The
+// unknown keys are rejected so a typo does not go unnoticed
+func ParseConfig(data []byte, format string) (*Config, error) {
+	switch strings.ToLower(format) {
+	case "json":
+	case "yaml", "yml":
+		var doc interface{}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+
+		// the yaml document is decoded through json, so both formats share
+		// the json tags
+		var err error
+		data, err = json.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown config format %q", format)
+	}
+
+	config := &Config{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(config); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// Validate checks the whole config, the error names the invalid key, e.g.
+// loggers.api.sinks[0].level: unknown logging level "verbose"
+func (c *Config) Validate() error {
+	if c.Global != nil {
+		if err := c.Global.validate(globalLoggerName); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range c.loggerNames() {
+		if name == "" {
+			return errors.New("loggers: empty logger name")
+		}
+
+		if err := c.Loggers[name].validate("loggers." + name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) loggerNames() []string {
+	names := make([]string, 0, len(c.Loggers))
+	for name := range c.Loggers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func (c *LoggerConfig) validate(path string) error {
+	if c == nil {
+		return fmt.Errorf("%s: empty logger", path)
+	}
+
+	if c.Level != "" {
+		if _, err := ParseLevel(c.Level); err != nil {
+			return fmt.Errorf("%s.level: %v", path, err)
+		}
+	}
+
+	if c.Stack != "" {
+		if _, err := ParseLevel(c.Stack); err != nil {
+			return fmt.Errorf("%s.stack: %v", path, err)
+		}
+	}
+
+	if err := c.Formatter.validate(path + ".formatter"); err != nil {
+		return err
+	}
+
+	for _, pattern := range c.modulePatterns() {
+		if err := validModulePattern(pattern); err != nil {
+			return fmt.Errorf("%s.modules: %v", path, err)
+		}
+
+		if _, err := ParseLevel(c.Modules[pattern]); err != nil {
+			return fmt.Errorf("%s.modules.%s: %v", path, pattern, err)
+		}
+	}
+
+	for i, s := range c.Sinks {
+		if err := s.validate(fmt.Sprintf("%s.sinks[%d]", path, i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *LoggerConfig) modulePatterns() []string {
+	patterns := make([]string, 0, len(c.Modules))
+	for pattern := range c.Modules {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	return patterns
+}
+
+func (c *FormatterConfig) validate(path string) error {
+	if c == nil {
+		return nil
+	}
+
+	if c.JSON != (JSONFormatterOptions{}) && c.Type != "json" {
+		return fmt.Errorf("%s.json: only allowed with type \"json\"", path)
+	}
+
+	if c.Pattern != "" && c.Type != "pattern" {
+		return fmt.Errorf("%s.pattern: only allowed with type \"pattern\"", path)
+	}
+
+	switch c.Type {
+	case "", "text", "logfmt", "json":
+	case "pattern":
+		if _, err := PatternFormatter(c.Pattern); err != nil {
+			return fmt.Errorf("%s.pattern: %v", path, err)
+		}
+	default:
+		return fmt.Errorf("%s.type: unknown formatter %q, expected \"text\", \"json\", \"logfmt\" or \"pattern\"", path, c.Type)
+	}
+
+	return nil
+}
+
+func (c *SinkConfig) validate(path string) error {
+	if c == nil {
+		return fmt.Errorf("%s: empty sink", path)
+	}
+
+	if c.Level != "" {
+		if _, err := ParseLevel(c.Level); err != nil {
+			return fmt.Errorf("%s.level: %v", path, err)
+		}
+	}
+
+	if err := c.Formatter.validate(path + ".formatter"); err != nil {
+		return err
+	}
+
+	if c.Dedup != nil {
+		if _, err := c.Dedup.toDedupConfig(path + ".dedup"); err != nil {
+			return err
+		}
+	}
+
+	// only the section of the type may be set
+	sections := map[string]bool{
+		"file":    c.File != nil,
+		"syslog":  c.Syslog != nil,
+		"network": c.Network != nil,
+	}
+	for _, section := range []string{"file", "syslog", "network"} {
+		if sections[section] && section != c.Type {
+			return fmt.Errorf("%s.%s: not allowed in a %q sink", path, section, c.Type)
+		}
+	}
+
+	if c.Stream != "" && c.Type != "console" {
+		return fmt.Errorf("%s.stream: not allowed in a %q sink", path, c.Type)
+	}
+
+	switch c.Type {
+	case "console":
+		switch c.Stream {
+		case "", "stdout", "stderr":
+		default:
+			return fmt.Errorf("%s.stream: unknown stream %q, expected \"stdout\" or \"stderr\"", path, c.Stream)
+		}
+	case "file":
+		if c.File == nil {
+			return fmt.Errorf("%s.file: required by a file sink", path)
+		}
+
+		return validateRotateConfig(path+".file", c.File)
+	case "syslog":
+		if c.Syslog == nil {
+			return fmt.Errorf("%s.syslog: required by a syslog sink", path)
+		}
+
+		_, err := c.Syslog.toSyslogConfig(path + ".syslog")
+		return err
+	case "network":
+		if c.Network == nil {
+			return fmt.Errorf("%s.network: required by a network sink", path)
+		}
+
+		_, err := c.Network.toNetworkConfig(path + ".network")
+		return err
+	case "":
+		return fmt.Errorf("%s.type: required", path)
+	default:
+		return fmt.Errorf("%s.type: unknown sink %q, expected \"console\", \"file\", \"syslog\" or \"network\"", path, c.Type)
+	}
+
+	return nil
+}
+
+func (c *DedupSinkConfig) toDedupConfig(path string) (*DedupConfig, error) {
+	config := &DedupConfig{Windowed: c.Windowed}
+
+	if c.Interval != "" {
+		interval, err := time.ParseDuration(c.Interval)
+		if err != nil {
+			return nil, fmt.Errorf("%s.interval: %v", path, err)
+		}
+
+		if interval <= 0 {
+			return nil, fmt.Errorf("%s.interval: must be positive", path)
+		}
+		config.Interval = interval
+	}
+
+	return config, nil
+}
+
+func validateRotateConfig(path string, c *LogRotateConfig) error {
+	if c.FileDir == "" {
+		return fmt.Errorf("%s.fileDir: required", path)
+	}
+
+	if c.Prefix == "" {
+		return fmt.Errorf("%s.prefix: required", path)
+	}
+
+	// a file rotated by time does not need a size limit
+	if c.MaxSize <= 0 && c.RotateInterval == "" {
+		return fmt.Errorf("%s.maxSize: must be positive without a rotateInterval", path)
+	}
+
+	if c.MaxLogLife < 0 {
+		return fmt.Errorf("%s.maxLogLife: must not be negative", path)
+	}
+
+	if c.MaxBackups < 0 {
+		return fmt.Errorf("%s.maxBackups: must not be negative", path)
+	}
+
+	if c.MaxTotalSize < 0 {
+		return fmt.Errorf("%s.maxTotalSize: must not be negative", path)
+	}
+
+	if c.MinKeep < 0 {
+		return fmt.Errorf("%s.minKeep: must not be negative", path)
+	}
+
+	if c.Compress != "" {
+		if _, ok := getCodec(c.Compress); !ok {
+			return fmt.Errorf("%s.compress: unknown codec %q", path, c.Compress)
+		}
+	}
+
+	if _, err := parseRotateInterval(c.RotateInterval); err != nil {
+		return fmt.Errorf("%s.rotateInterval: %v", path, err)
+	}
+
+	return nil
+}
+
+func (c *SyslogSinkConfig) toSyslogConfig(path string) (SyslogConfig, error) {
+	config := SyslogConfig{
+		Network:  c.Network,
+		Address:  c.Address,
+		AppName:  c.AppName,
+		Hostname: c.Hostname,
+	}
+
+	if (c.Network == "") != (c.Address == "") {
+		return config, fmt.Errorf("%s: network and address must be set together", path)
+	}
+
+	if c.Facility != "" {
+		facility, ok := syslogFacilities[strings.ToLower(c.Facility)]
+		if !ok {
+			return config, fmt.Errorf("%s.facility: unknown facility %q", path, c.Facility)
+		}
+		config.Facility = &facility
+	}
+
+	switch strings.ToLower(c.Format) {
+	case "", "rfc5424":
+		config.Format = RFC5424
+	case "rfc3164":
+		config.Format = RFC3164
+	default:
+		return config, fmt.Errorf("%s.format: unknown format %q, expected \"rfc5424\" or \"rfc3164\"", path, c.Format)
+	}
+
+	if !c.TLS {
+		if c.CAFile != "" {
+			return config, fmt.Errorf("%s.caFile: only allowed with tls", path)
+		}
+
+		return config, nil
+	}
+
+	if c.Network != "tcp" {
+		return config, fmt.Errorf("%s.tls: only allowed with network \"tcp\"", path)
+	}
+
+	config.TLSConfig = &tls.Config{}
+	if c.CAFile != "" {
+		pem, err := ioutil.ReadFile(c.CAFile)
+		if err != nil {
+			return config, fmt.Errorf("%s.caFile: %v", path, err)
+		}
+
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return config, fmt.Errorf("%s.caFile: no certificate found in %s", path, c.CAFile)
+		}
+		config.TLSConfig.RootCAs = roots
+	}
+
+	return config, nil
+}
+
+func (c *NetworkSinkConfig) toNetworkConfig(path string) (NetworkConfig, error) {
+	config := NetworkConfig{
+		Network:    c.Network,
+		Address:    c.Address,
+		BufferSize: c.BufferSize,
+	}
+
+	switch c.Network {
+	case "tcp", "tcp4", "tcp6", "unix":
+	case "":
+		return config, fmt.Errorf("%s.network: required", path)
+	default:
+		return config, fmt.Errorf("%s.network: unsupported network %q, expected \"tcp\" or \"unix\"", path, c.Network)
+	}
+
+	if c.Address == "" {
+		return config, fmt.Errorf("%s.address: required", path)
+	}
+
+	switch c.Framing {
+	case "", "newline":
+		config.Framing = FRAMING_NEWLINE
+	case "length":
+		config.Framing = FRAMING_LENGTH_PREFIX
+	default:
+		return config, fmt.Errorf("%s.framing: unknown framing %q, expected \"newline\" or \"length\"", path, c.Framing)
+	}
+
+	if c.BufferSize < 0 {
+		return config, fmt.Errorf("%s.bufferSize: must not be negative", path)
+	}
+
+	durations := []struct {
+		key   string
+		value string
+		dst   *time.Duration
+	}{
+		{"dialTimeout", c.DialTimeout, &config.DialTimeout},
+		{"writeTimeout", c.WriteTimeout, &config.WriteTimeout},
+		{"minBackoff", c.MinBackoff, &config.MinBackoff},
+		{"maxBackoff", c.MaxBackoff, &config.MaxBackoff},
+	}
+
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+
+		value, err := time.ParseDuration(d.value)
+		if err != nil {
+			return config, fmt.Errorf("%s.%s: %v", path, d.key, err)
+		}
+
+		if value <= 0 {
+			return config, fmt.Errorf("%s.%s: must be positive", path, d.key)
+		}
+		*d.dst = value
+	}
+
+	return config, nil
+}
+
+// ApplyConfig validates config and applies it, nothing is changed when it
+// is invalid. Applying a config again replaces the sinks, the formatter and
+// the module levels of the loggers it describes
+func ApplyConfig(config *Config) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	b := &configBuilder{formatters: map[FormatterConfig]Formatter{}}
+
+	if config.Global != nil {
+		if err := b.build(globalLoggerName, "", config.Global); err != nil {
+			b.closeOutputs()
+			return err
+		}
+	}
+
+	for _, name := range config.loggerNames() {
+		if err := b.build("loggers."+name, name, config.Loggers[name]); err != nil {
+			b.closeOutputs()
+			return err
+		}
+	}
+
+	for _, built := range b.loggers {
+		built.apply()
+	}
+
+	return nil
+}
+
+// configBuilder opens the outputs of every logger before any of them is
+// changed, so a failing output leaves the loggers untouched
+type configBuilder struct {
+	// the same formatter is shared by the identical configs
+	formatters map[FormatterConfig]Formatter
+	loggers    []*builtLogger
+}
+
+type builtLogger struct {
+	// empty for the global logger
+	name      string
+	level     LoggingLevel
+	formatter Formatter
+	modules   []moduleLevel
+	sinks     []Sink
+
+	stackEnabled bool
+	stackLevel   LoggingLevel
+}
+
+// key returns the config with its defaults, c may be nil
+func (c *FormatterConfig) key() FormatterConfig {
+	var key FormatterConfig
+	if c != nil {
+		key = *c
+	}
+
+	if key.Type == "" {
+		key.Type = "text"
+	}
+
+	return key
+}
+
+func (b *configBuilder) formatter(c *FormatterConfig, global bool) Formatter {
+	key := c.key()
+
+	switch key.Type {
+	case "text":
+		if global {
+			return globalLogFormatter
+		}
+
+		return DefaultFormater
+	case "logfmt":
+		return LogfmtFormatter
+	}
+
+	formatter, ok := b.formatters[key]
+	if !ok {
+		if key.Type == "pattern" {
+			// the pattern is checked by Validate
+			formatter, _ = PatternFormatter(key.Pattern)
+		} else {
+			formatter = JSONFormatter(key.JSON)
+		}
+		b.formatters[key] = formatter
+	}
+
+	return formatter
+}
+
+func (b *configBuilder) build(path, name string, c *LoggerConfig) error {
+	global := name == ""
+	built := &builtLogger{
+		name:      name,
+		level:     INFO_LEVEL,
+		formatter: b.formatter(c.Formatter, global),
+	}
+	b.loggers = append(b.loggers, built)
+
+	if c.Level != "" {
+		built.level, _ = ParseLevel(c.Level)
+	}
+
+	if c.Stack != "" {
+		built.stackEnabled = true
+		built.stackLevel, _ = ParseLevel(c.Stack)
+	}
+
+	for _, pattern := range c.modulePatterns() {
+		level, _ := ParseLevel(c.Modules[pattern])
+		built.modules = append(built.modules, moduleLevel{pattern: pattern, level: level})
+	}
+
+	for i, s := range c.Sinks {
+		output, err := s.open()
+		if err != nil {
+			return fmt.Errorf("%s.sinks[%d]: %v", path, i, err)
+		}
+
+		sink := Sink{Output: output}
+		if s.Level != "" {
+			sink.Level, _ = ParseLevel(s.Level)
+		}
+
+		// the sinks without a formatter share the buffer formatted for the
+		// logger
+		if s.Formatter != nil && s.Formatter.key() != c.Formatter.key() {
+			sink.Formatter = b.formatter(s.Formatter, global)
+		}
+
+		if s.Dedup != nil {
+			// checked by Validate
+			sink.Dedup, _ = s.Dedup.toDedupConfig("")
+		}
+
+		built.sinks = append(built.sinks, sink)
+	}
+
+	return nil
+}
+
+// closeOutputs closes the outputs opened by a build which failed
+func (b *configBuilder) closeOutputs() {
+	for _, built := range b.loggers {
+		for _, s := range built.sinks {
+			closeOutput(s.Output)
+		}
+	}
+}
+
+func (c *SinkConfig) open() (OutPut, error) {
+	switch c.Type {
+	case "console":
+		if c.Stream == "stderr" {
+			return &WriterOutput{os.Stderr}, nil
+		}
+
+		return &WriterOutput{os.Stdout}, nil
+	case "file":
+		output, err := NewFileOutput(*c.File)
+		if err != nil {
+			return nil, err
+		}
+
+		return output.(OutPut), nil
+	case "syslog":
+		config, err := c.Syslog.toSyslogConfig("syslog")
+		if err != nil {
+			return nil, err
+		}
+
+		return NewSyslogOutput(config)
+	case "network":
+		config, err := c.Network.toNetworkConfig("network")
+		if err != nil {
+			return nil, err
+		}
+
+		return NewNetworkOutput(config), nil
+	default:
+		return nil, fmt.Errorf("unknown sink %q", c.Type)
+	}
+}
+
+// closeOutput closes output unless it is a console
+func closeOutput(output OutPut) {
+	if _, ok := output.(*WriterOutput); ok {
+		return
+	}
+
+	if closer, ok := output.(io.Closer); ok {
+		closer.Close()
+	}
+}
+
+// apply configures the logger, a named logger is created and registered if
+// it does not exist
+func (built *builtLogger) apply() {
+	l := logger
+	if built.name != "" {
+		var ok bool
+		if l, ok = Lookup(built.name); !ok {
+			l = NewLogging(built.name, INFO_LEVEL, 4)
+			Register(built.name, l)
+		}
+	}
+
+	// the output and the sinks are swapped at once, so no record is lost
+	// in between. writeMux is held so the log file is no longer written once
+	// it is replaced
+	l.writeMux.Lock()
+	l.mux.Lock()
+	l.level = built.level
+	l.Formater = built.formatter
+	l.moduleLevels = built.modules
+	l.storeLevels()
+	l.stackEnabled = built.stackEnabled
+	l.stackLevel = built.stackLevel
+	var fileOutput *FileOutput
+	if len(built.sinks) != 0 {
+		// every record goes through the sinks, the log file opened by
+		// Start is closed
+		fileOutput = l.fileOutput
+		l.fileOutput = nil
+		l.output = nil
+	} else if l.output == nil {
+		l.output = os.Stdout
+	}
+	old := l.replaceSinks(built.sinks)
+	l.mux.Unlock()
+	l.writeMux.Unlock()
+
+	if fileOutput != nil {
+		fileOutput.Close()
+	}
+
+	// the records already dispatched to the old sinks, including the ones
+	// queued in async mode, are written before their outputs are closed
+	for _, s := range old {
+		s.retire()
+	}
+
+	// only the sinks are rotated, Start could open the log file again
+	if len(built.sinks) != 0 {
+		l.startRotate()
+	}
+}
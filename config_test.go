@@ -0,0 +1,359 @@
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func readLogDir(dir string) (string, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	content := ""
+	for _, file := range files {
+		data, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return "", err
+		}
+		content += string(data)
+	}
+
+	return content, nil
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	logDir := filepath.Join(dir, "logs")
+	yamlConfig := `
+loggers:
+  yaml-test:
+    level: warn
+    stack: error
+    formatter:
+      type: json
+      json:
+        messageKey: message
+    modules:
+      db.*: debug
+    sinks:
+      - type: file
+        file:
+          fileDir: ` + logDir + `
+          prefix: yaml
+          maxSize: 10
+        dedup:
+          interval: 1m
+`
+	jsonConfig := `{
+  "loggers": {
+    "json-test": {
+      "level": "info",
+      "sinks": [
+        {
+          "type": "file",
+          "level": "error",
+          "formatter": {"type": "logfmt"},
+          "file": {"fileDir": "` + logDir + `", "prefix": "json", "maxSize": 10}
+        }
+      ]
+    }
+  }
+}`
+
+	yamlFile := filepath.Join(dir, "log.yaml")
+	jsonFile := filepath.Join(dir, "log.json")
+	if err := ioutil.WriteFile(yamlFile, []byte(yamlConfig), 0644); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := ioutil.WriteFile(jsonFile, []byte(jsonConfig), 0644); err != nil {
+		t.Error(err)
+		return
+	}
+
+	for _, file := range []string{yamlFile, jsonFile} {
+		if err := LoadConfig(file); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	// applying the same config again must not add sinks
+	if err := LoadConfig(yamlFile); err != nil {
+		t.Error(err)
+		return
+	}
+
+	yamlLogger, ok := Lookup("yaml-test")
+	if !ok {
+		t.Error("yaml-test is not registered")
+		return
+	}
+	defer Unregister("yaml-test")
+
+	jsonLogger, ok := Lookup("json-test")
+	if !ok {
+		t.Error("json-test is not registered")
+		return
+	}
+	defer Unregister("json-test")
+
+	if len(yamlLogger.sinks) != 1 {
+		t.Errorf("expected 1 sink, got %d", len(yamlLogger.sinks))
+	}
+
+	if !yamlLogger.stackEnabled || yamlLogger.stackLevel != ERROR_LEVEL {
+		t.Errorf("expected the stack at %v, got %v at %v", ERROR_LEVEL, yamlLogger.stackEnabled, yamlLogger.stackLevel)
+	}
+
+	yamlLogger.Info("dropped")
+	for i := 0; i < 3; i++ {
+		yamlLogger.Warn("kept")
+	}
+	yamlLogger.Module("db.query").Debug("query")
+	jsonLogger.Warn("dropped")
+	jsonLogger.Error("failed")
+
+	for _, l := range []*logging{yamlLogger, jsonLogger} {
+		for _, s := range l.setSinks(nil) {
+			s.retire()
+		}
+	}
+
+	content, err := readLogDir(logDir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// the pending repetitions are summarized when the sinks are removed
+	expected := []string{`"message":"kept"`, `"message":"last message repeated 2 times"`, `"message":"query"`, `msg=failed`}
+	for _, expected := range expected {
+		if !strings.Contains(content, expected) {
+			t.Errorf("%s is missing in %s", expected, content)
+		}
+	}
+
+	if strings.Contains(content, "dropped") {
+		t.Errorf("unexpected record in %s", content)
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	cases := []struct {
+		config   string
+		expected string
+	}{
+		{
+			`{"loggers": {"api": {"sinks": [{"type": "console", "level": "verbose"}]}}}`,
+			`loggers.api.sinks[0].level: unknown logging level "verbose"`,
+		},
+		{
+			`{"loggers": {"api": {"stack": "severe"}}}`,
+			`loggers.api.stack: unknown logging level "severe"`,
+		},
+		{
+			`{"global": {"formatter": {"type": "xml"}}}`,
+			`global.formatter.type: unknown formatter "xml"`,
+		},
+		{
+			`{"loggers": {"api": {"formatter": {"type": "pattern", "pattern": "%date %msg"}}}}`,
+			`loggers.api.formatter.pattern: unknown verb "%date"`,
+		},
+		{
+			`{"loggers": {"api": {"modules": {"db*": "debug"}}}}`,
+			`loggers.api.modules: invalid module pattern "db*"`,
+		},
+		{
+			`{"loggers": {"api": {"sinks": [{"type": "file"}]}}}`,
+			`loggers.api.sinks[0].file: required by a file sink`,
+		},
+		{
+			`{"loggers": {"api": {"sinks": [{"type": "file", "file": {"fileDir": "logs", "prefix": "api"}}]}}}`,
+			`loggers.api.sinks[0].file.maxSize: must be positive without a rotateInterval`,
+		},
+		{
+			`{"loggers": {"api": {"sinks": [{"type": "file", "file": {"fileDir": "logs", "prefix": "api", "maxSize": 10, "rotateInterval": "weekly"}}]}}}`,
+			`loggers.api.sinks[0].file.rotateInterval: `,
+		},
+		{
+			`{"loggers": {"api": {"sinks": [{"type": "syslog", "syslog": {"facility": "local9"}}]}}}`,
+			`loggers.api.sinks[0].syslog.facility: unknown facility "local9"`,
+		},
+		{
+			`{"loggers": {"api": {"sinks": [{"type": "network", "network": {"network": "tcp", "address": "localhost:514", "minBackoff": "soon"}}]}}}`,
+			`loggers.api.sinks[0].network.minBackoff: `,
+		},
+		{
+			`{"loggers": {"api": {"sinks": [{"type": "network", "network": {"network": "tcp", "address": "localhost:514", "writeTimeout": "-1s"}}]}}}`,
+			`loggers.api.sinks[0].network.writeTimeout: must be positive`,
+		},
+		{
+			`{"loggers": {"api": {"sinks": [{"type": "console", "dedup": {"interval": "often"}}]}}}`,
+			`loggers.api.sinks[0].dedup.interval: `,
+		},
+		{
+			`{"loggers": {"api": {"sinks": [{"type": "console", "file": {}}]}}}`,
+			`loggers.api.sinks[0].file: not allowed in a "console" sink`,
+		},
+	}
+
+	for _, c := range cases {
+		config, err := ParseConfig([]byte(c.config), "json")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		err = config.Validate()
+		if err == nil || !strings.HasPrefix(err.Error(), c.expected) {
+			t.Errorf("expected %s, got %v", c.expected, err)
+		}
+	}
+
+	if _, err := ParseConfig([]byte(`{"loggers": {"api": {"levl": "info"}}}`), "json"); err == nil {
+		t.Error("expected an error for an unknown key")
+	}
+}
+
+// closingOutput is a blockingOutput recording when it is closed
+type closingOutput struct {
+	*blockingOutput
+
+	mux    sync.Mutex
+	writes int
+	closed bool
+	// a write came after Close
+	lateWrite bool
+}
+
+func (o *closingOutput) Write(p []byte) (int, error) {
+	n, err := o.blockingOutput.Write(p)
+
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
+	o.writes++
+	if o.closed {
+		o.lateWrite = true
+	}
+
+	return n, err
+}
+
+func (o *closingOutput) Rotate() error {
+	return nil
+}
+
+func (o *closingOutput) Close() error {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
+	o.closed = true
+	return nil
+}
+
+func (o *closingOutput) state() (int, bool, bool) {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
+	return o.writes, o.closed, o.lateWrite
+}
+
+func TestApplyConfigClosesAfterWrite(t *testing.T) {
+	old := &closingOutput{blockingOutput: newBlockingOutput()}
+	current := &BufferOutput{}
+
+	logging := NewLogging("apply-test", INFO_LEVEL, 4)
+	Register("apply-test", logging)
+	defer Unregister("apply-test")
+
+	logging.SetOutPut(nil)
+	logging.AddSink(Sink{Output: old})
+	logging.EnableAsync(AsyncConfig{})
+	defer logging.Close()
+
+	logging.Info("first")
+	logging.Info("second")
+
+	built := &builtLogger{name: "apply-test", level: INFO_LEVEL, formatter: DefaultFormater, sinks: []Sink{{Output: current}}}
+	built.apply()
+	logging.Info("third")
+
+	if _, closed, _ := old.state(); closed {
+		t.Error("the old sink is closed before its records are written")
+	}
+
+	close(old.release)
+	logging.Flush()
+
+	writes, closed, lateWrite := old.state()
+	if writes != 2 || !closed || lateWrite {
+		t.Errorf("unexpected old sink: %d writes, closed %v, late write %v", writes, closed, lateWrite)
+	}
+
+	if !strings.Contains(current.String(), "third") || strings.Contains(current.String(), "first") {
+		t.Errorf("unexpected output %q", current.String())
+	}
+}
+
+func TestApplyConfigClosesLogFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	current := &BufferOutput{}
+
+	logging := NewLogging("apply-file-test", INFO_LEVEL, 4)
+	Register("apply-file-test", logging)
+	defer Unregister("apply-file-test")
+
+	logging.UpdateConfig(LogRotateConfig{EnableLogFile: true, Prefix: "test", FileDir: dir, MaxSize: 10})
+	logging.Start()
+	defer logging.Close()
+
+	fileOutput := logging.fileOutput
+	if fileOutput == nil {
+		t.Error("the log file is not opened")
+		return
+	}
+
+	built := &builtLogger{name: "apply-file-test", level: INFO_LEVEL, formatter: DefaultFormater, sinks: []Sink{{Output: current}}}
+	built.apply()
+	logging.Info("sink record")
+
+	// the log file is closed and not opened again
+	if _, err := fileOutput.WriteString("late"); err == nil {
+		t.Error("the log file is not closed")
+	}
+
+	if logging.fileOutput != nil || logging.output != nil {
+		t.Error("the log file is opened again")
+	}
+
+	content, err := readLogDir(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if strings.Contains(content, "sink record") || !strings.Contains(current.String(), "sink record") {
+		t.Errorf("unexpected records %q %q", content, current.String())
+	}
+}
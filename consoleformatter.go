@@ -0,0 +1,160 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ColorMode decides whether ConsoleFormatter colours the levels
+type ColorMode int
+
+const (
+	// colour when the output is a terminal and NO_COLOR is not set
+	COLOR_AUTO ColorMode = iota
+	COLOR_ALWAYS
+	COLOR_NEVER
+)
+
+type ConsoleFormatterOptions struct {
+	// the output the records are written to, it is used to detect a
+	// terminal. COLOR_AUTO does not colour the records when it is not set,
+	// the formatter can not know where they go
+	Output io.Writer
+	Color  ColorMode
+	// the module column is padded to ModuleWidth, 12 if it is not set
+	ModuleWidth int
+	// number of trailing path segments kept in the caller, 2 if it is not
+	// set, e.g. "log/output.go:12"
+	CallerSegments int
+}
+
+const (
+	defaultModuleWidth    = 12
+	defaultCallerSegments = 2
+	// the width of the longest level name
+	levelWidth = 5
+)
+
+const colorReset = "\x1b[0m"
+
+var levelColors = map[LoggingLevel]string{
+	TRACE_LEVEL: "\x1b[90m",
+	DEBUG_LEVEL: "\x1b[36m",
+	INFO_LEVEL:  "\x1b[32m",
+	WARN_LEVEL:  "\x1b[33m",
+	ERROR_LEVEL: "\x1b[31m",
+	FATAL_LEVEL: "\x1b[1;31m",
+}
+
+// isTerminal reports whether w is a character device such as a terminal
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+func (o *ConsoleFormatterOptions) useColor() bool {
+	switch o.Color {
+	case COLOR_ALWAYS:
+		return true
+	case COLOR_NEVER:
+		return false
+	}
+
+	// https://no-color.org
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	return o.Output != nil && isTerminal(o.Output)
+}
+
+// shortCaller keeps the last segments of the path of file
+func shortCaller(file string, segments int) string {
+	i := len(file)
+	for n := 0; n < segments; n++ {
+		i = strings.LastIndexByte(file[:i], '/')
+		if i < 0 {
+			return file
+		}
+	}
+
+	return file[i+1:]
+}
+
+func writePadded(buf *bytes.Buffer, s string, width int) {
+	buf.WriteString(s)
+	for i := len(s); i < width; i++ {
+		buf.WriteByte(' ')
+	}
+}
+
+// ConsoleFormatter returns a Formatter for reading the records in a
+// terminal, the levels are coloured and the level and module columns are
+// aligned. The colours are decided once, when the formatter is created
+func ConsoleFormatter(opts ConsoleFormatterOptions) Formatter {
+	if opts.ModuleWidth <= 0 {
+		opts.ModuleWidth = defaultModuleWidth
+	}
+
+	if opts.CallerSegments <= 0 {
+		opts.CallerSegments = defaultCallerSegments
+	}
+
+	color := opts.useColor()
+
+	return func(logRecord *LogRecord) *bytes.Buffer {
+		caller, line := logRecord.caller()
+
+		buf := pool.Get()
+		buf.Reset()
+
+		buf.WriteString(formatCacheTime(logRecord.timestamp()))
+		buf.WriteString(" ")
+
+		if color {
+			buf.WriteString(levelColors[logRecord.logLevel])
+		}
+		writePadded(buf, logRecord.logLevel.String(), levelWidth)
+		if color {
+			buf.WriteString(colorReset)
+		}
+
+		buf.WriteString(" [")
+		writePadded(buf, logRecord.module, opts.ModuleWidth)
+		buf.WriteString("] ")
+
+		if logRecord.enableCaller {
+			buf.WriteString(shortCaller(caller, opts.CallerSegments))
+			buf.WriteString(":")
+			buf.WriteString(strconv.Itoa(line))
+			buf.WriteString(" ")
+			if len(logRecord.function) != 0 {
+				buf.WriteString(logRecord.function)
+				buf.WriteString(" ")
+			}
+		}
+		buf.WriteString(logRecord.message())
+
+		if logRecord.err != nil {
+			buf.WriteString(" error=")
+			writeLogfmtString(buf, logRecord.err.Error())
+		}
+		writeFields(buf, logRecord.fields)
+		buf.WriteString("\n")
+		writeStack(buf, logRecord.stack)
+
+		return buf
+	}
+}
@@ -0,0 +1,100 @@
+package log
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestConsoleFormatter(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logging := NewLogging("test", INFO_LEVEL, 4)
+	logging.SetOutPut(buf)
+	logging.SetFormatter(ConsoleFormatter(ConsoleFormatterOptions{
+		Output:         buf,
+		Color:          COLOR_ALWAYS,
+		CallerSegments: 1,
+	}))
+
+	logging.Module("db").Warn("slow query")
+	logging.Info("started")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Errorf("expected 2 lines, got %q", lines)
+		return
+	}
+
+	expected := []string{
+		"\x1b[33mWarn \x1b[0m [db          ] ",
+		"\x1b[32mInfo \x1b[0m [            ] ",
+	}
+	for i := range expected {
+		if !strings.Contains(lines[i], expected[i]) {
+			t.Errorf("expected %q in %q", expected[i], lines[i])
+		}
+	}
+
+	if !strings.HasSuffix(lines[0], " slow query") {
+		t.Errorf("unexpected line %q", lines[0])
+	}
+}
+
+func TestConsoleFormatterColor(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	cases := []struct {
+		color    ColorMode
+		noColor  string
+		expected bool
+	}{
+		{COLOR_ALWAYS, "1", true},
+		{COLOR_NEVER, "", false},
+		// a bytes.Buffer is not a terminal
+		{COLOR_AUTO, "", false},
+	}
+
+	// the records may go to a file, whatever os.Stdout is
+	if (&ConsoleFormatterOptions{}).useColor() {
+		t.Error("expected no colour without an output")
+	}
+
+	// restore the environment of the developer
+	noColor, ok := os.LookupEnv("NO_COLOR")
+	defer func() {
+		if ok {
+			os.Setenv("NO_COLOR", noColor)
+		} else {
+			os.Unsetenv("NO_COLOR")
+		}
+	}()
+
+	for _, c := range cases {
+		os.Setenv("NO_COLOR", c.noColor)
+		opts := ConsoleFormatterOptions{Output: buf, Color: c.color}
+		if opts.useColor() != c.expected {
+			t.Errorf("expected %v for %v with NO_COLOR=%q", c.expected, c.color, c.noColor)
+		}
+	}
+}
+
+func TestShortCaller(t *testing.T) {
+	cases := []struct {
+		file     string
+		segments int
+		expected string
+	}{
+		{"/root/module/output.go", 2, "module/output.go"},
+		{"/root/module/output.go", 1, "output.go"},
+		{"output.go", 2, "output.go"},
+		{"/output.go", 2, "/output.go"},
+	}
+
+	for _, c := range cases {
+		if s := shortCaller(c.file, c.segments); s != c.expected {
+			t.Errorf("expected %s, got %s", c.expected, s)
+		}
+	}
+}
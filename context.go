@@ -0,0 +1,175 @@
+package log
+
+import (
+	"context"
+	"sync"
+)
+
+// ContextExtractor returns the fields carried by ctx, e.g. a request id
+// stored by a middleware
+type ContextExtractor func(ctx context.Context) []Field
+
+// registeredExtractor is an extractor and the id removing it
+type registeredExtractor struct {
+	id        uint64
+	extractor ContextExtractor
+}
+
+var (
+	extractorMux    sync.RWMutex
+	extractors      []registeredExtractor
+	lastExtractorID uint64
+)
+
+// RegisterContextExtractor adds an extractor whose fields are added to every
+// record logged with a context, the returned func removes it
+func RegisterContextExtractor(extractor ContextExtractor) func() {
+	extractorMux.Lock()
+	defer extractorMux.Unlock()
+
+	lastExtractorID++
+	id := lastExtractorID
+	extractors = append(extractors, registeredExtractor{id: id, extractor: extractor})
+
+	return func() {
+		extractorMux.Lock()
+		defer extractorMux.Unlock()
+
+		for i, e := range extractors {
+			if e.id == id {
+				extractors = append(extractors[:i:i], extractors[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+type loggerKey struct{}
+
+type fieldsKey struct{}
+
+// NewContext returns a copy of ctx carrying l
+func NewContext(ctx context.Context, l *logging) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the logging carried by ctx, or the global one
+func FromContext(ctx context.Context) *logging {
+	if ctx != nil {
+		if l, ok := ctx.Value(loggerKey{}).(*logging); ok {
+			return l
+		}
+	}
+
+	return logger
+}
+
+// ContextWithFields returns a copy of ctx carrying the given alternating
+// keys and values in addition to the fields already carried by ctx
+func ContextWithFields(ctx context.Context, keyValues ...interface{}) context.Context {
+	parent, _ := ctx.Value(fieldsKey{}).([]Field)
+	return context.WithValue(ctx, fieldsKey{}, mergeFields(parent, toFields(keyValues)))
+}
+
+// contextFields returns the fields carried by ctx followed by the fields of
+// the registered extractors
+func contextFields(ctx context.Context) []Field {
+	if ctx == nil {
+		return nil
+	}
+
+	fields, _ := ctx.Value(fieldsKey{}).([]Field)
+
+	extractorMux.RLock()
+	defer extractorMux.RUnlock()
+
+	for _, e := range extractors {
+		fields = mergeFields(fields, e.extractor(ctx))
+	}
+
+	return fields
+}
+
+func (l *logging) Ctx(ctx context.Context) *LogRecord {
+	return &LogRecord{
+		callerLevel:  l.callerLevel,
+		enableCaller: l.enableCaller,
+		logger:       l,
+		fields:       contextFields(ctx),
+	}
+}
+
+// Ctx returns a child record extended by the fields carried by ctx
+func (l *LogRecord) Ctx(ctx context.Context) *LogRecord {
+	child := *l
+	child.fields = mergeFields(l.fields, contextFields(ctx))
+	return &child
+}
+
+func (l *logging) printContext(ctx context.Context, level LoggingLevel, args ...interface{}) {
+	if l.levelOf("") <= level {
+		record := &LogRecord{
+			logLevel:     level,
+			callerLevel:  l.callerLevel,
+			enableCaller: l.enableCaller,
+			logger:       l,
+			fields:       contextFields(ctx),
+		}
+
+		record.print(args...)
+	}
+}
+
+func (l *logging) printfContext(ctx context.Context, level LoggingLevel, format string, args ...interface{}) {
+	if l.levelOf("") <= level {
+		record := &LogRecord{
+			logLevel:     level,
+			callerLevel:  l.callerLevel,
+			enableCaller: l.enableCaller,
+			logger:       l,
+			fields:       contextFields(ctx),
+		}
+
+		record.printf(format, args...)
+	}
+}
+
+func (l *logging) TraceContext(ctx context.Context, args ...interface{}) {
+	l.printContext(ctx, TRACE_LEVEL, args...)
+}
+
+func (l *logging) DebugContext(ctx context.Context, args ...interface{}) {
+	l.printContext(ctx, DEBUG_LEVEL, args...)
+}
+
+func (l *logging) InfoContext(ctx context.Context, args ...interface{}) {
+	l.printContext(ctx, INFO_LEVEL, args...)
+}
+
+func (l *logging) WarnContext(ctx context.Context, args ...interface{}) {
+	l.printContext(ctx, WARN_LEVEL, args...)
+}
+
+func (l *logging) ErrorContext(ctx context.Context, args ...interface{}) {
+	l.printContext(ctx, ERROR_LEVEL, args...)
+}
+
+func (l *logging) TracefContext(ctx context.Context, format string, args ...interface{}) {
+	l.printfContext(ctx, TRACE_LEVEL, format, args...)
+}
+
+func (l *logging) DebugfContext(ctx context.Context, format string, args ...interface{}) {
+	l.printfContext(ctx, DEBUG_LEVEL, format, args...)
+}
+
+func (l *logging) InfofContext(ctx context.Context, format string, args ...interface{}) {
+	l.printfContext(ctx, INFO_LEVEL, format, args...)
+}
+
+func (l *logging) WarnfContext(ctx context.Context, format string, args ...interface{}) {
+	l.printfContext(ctx, WARN_LEVEL, format, args...)
+}
+
+func (l *logging) ErrorfContext(ctx context.Context, format string, args ...interface{}) {
+	l.printfContext(ctx, ERROR_LEVEL, format, args...)
+}
@@ -0,0 +1,78 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+type tenantKey struct{}
+
+func TestContextFields(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	unregister := RegisterContextExtractor(func(ctx context.Context) []Field {
+		if tenant, ok := ctx.Value(tenantKey{}).(string); ok {
+			return []Field{{Key: "tenant", Value: tenant}}
+		}
+
+		return nil
+	})
+	t.Cleanup(unregister)
+
+	logging := NewLogging("test", INFO_LEVEL, 4)
+	logging.SetOutPut(buf)
+
+	ctx := ContextWithFields(context.Background(), "request", "abc")
+	ctx = context.WithValue(ctx, tenantKey{}, "acme")
+
+	logging.InfoContext(ctx, "Test Message")
+	if !strings.Contains(buf.String(), "Test Message request=abc tenant=acme\n") {
+		t.Errorf("unexpected output %q", buf.String())
+		return
+	}
+
+	buf.Reset()
+	logging.With("user", 42).Ctx(ctx).Warnf("Test %s", "Message")
+	if !strings.Contains(buf.String(), "Warn msg: Test Message user=42 request=abc tenant=acme\n") {
+		t.Errorf("unexpected output %q", buf.String())
+		return
+	}
+
+	buf.Reset()
+	logging.DebugContext(ctx, "Test Message")
+	if buf.Len() != 0 {
+		t.Error("test level failed")
+	}
+}
+
+func TestLoggerContext(t *testing.T) {
+	logging := NewLogging("test", INFO_LEVEL, 4)
+
+	ctx := NewContext(context.Background(), logging)
+	if FromContext(ctx) != logging {
+		t.Error("logging is not carried by context")
+	}
+
+	if FromContext(context.Background()) != logger {
+		t.Error("expected global logging for empty context")
+	}
+}
+
+func TestUnregisterContextExtractor(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logging := NewLogging("test", INFO_LEVEL, 4)
+	logging.SetOutPut(buf)
+
+	unregister := RegisterContextExtractor(func(ctx context.Context) []Field {
+		return []Field{{Key: "extracted", Value: true}}
+	})
+	unregister()
+
+	logging.InfoContext(context.Background(), "Test Message")
+	if strings.Contains(buf.String(), "extracted") {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+}
@@ -0,0 +1,238 @@
+package log
+
+import (
+	"sync"
+	"time"
+)
+
+type DedupConfig struct {
+	// the duplicates of a record are collapsed for Interval after it is
+	// written, then a summary is written and the next duplicate is written
+	// again. 10 seconds if it is not set
+	Interval time.Duration `json:"interval"`
+	// collapse the identical records even when other records are written
+	// in between, by default only the consecutive ones are collapsed and a
+	// different record ends the repetition
+	Windowed bool `json:"windowed"`
+}
+
+const defaultDedupInterval = 10 * time.Second
+
+// dedupKey identifies the identical records
+type dedupKey struct {
+	level   LoggingLevel
+	module  string
+	message string
+	file    string
+	line    int
+}
+
+type dedupEntry struct {
+	// the first record, the summary is logged with its caller
+	record   *LogRecord
+	repeated int
+	timer    *time.Timer
+}
+
+// deduper collapses the identical records written to one output
+type deduper struct {
+	config DedupConfig
+	logger *logging
+	// nil for the default output of logger
+	sink *sink
+
+	mux     sync.Mutex
+	entries map[dedupKey]*dedupEntry
+}
+
+func newDeduper(logger *logging, s *sink, config DedupConfig) *deduper {
+	if config.Interval <= 0 {
+		config.Interval = defaultDedupInterval
+	}
+
+	return &deduper{
+		config:  config,
+		logger:  logger,
+		sink:    s,
+		entries: map[dedupKey]*dedupEntry{},
+	}
+}
+
+// summary returns the record saying how many times e was repeated
+func (e *dedupEntry) summary() *LogRecord {
+	summary := *e.record
+	summary.format = "last message repeated %d times"
+	summary.args = []interface{}{e.repeated}
+	summary.fields = nil
+	summary.err = nil
+	summary.stack = nil
+	// the summary is printed with the time it is written
+	summary.time = time.Time{}
+
+	return &summary
+}
+
+// check reports whether record is written, the summaries of the
+// repetitions it ends are returned so they are written before it
+func (d *deduper) check(key dedupKey, record *LogRecord) (bool, []*LogRecord) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	var summaries []*LogRecord
+
+	if !d.config.Windowed {
+		for k, e := range d.entries {
+			if k == key {
+				continue
+			}
+
+			e.timer.Stop()
+			delete(d.entries, k)
+			if e.repeated > 0 {
+				summaries = append(summaries, e.summary())
+			}
+		}
+	}
+
+	if e, ok := d.entries[key]; ok {
+		e.repeated++
+		return false, summaries
+	}
+
+	e := &dedupEntry{record: record}
+	e.timer = time.AfterFunc(d.config.Interval, func() {
+		d.expire(key, e)
+	})
+	d.entries[key] = e
+
+	return true, summaries
+}
+
+// expire ends the repetition of key once its interval is over
+func (d *deduper) expire(key dedupKey, e *dedupEntry) {
+	d.mux.Lock()
+	if d.entries[key] != e {
+		d.mux.Unlock()
+		return
+	}
+	delete(d.entries, key)
+	repeated := e.repeated
+	d.mux.Unlock()
+
+	if repeated > 0 {
+		d.logger.writeSummary(d.sink, e.summary())
+	}
+}
+
+// flush ends the pending repetitions and writes their summaries, the next
+// duplicate is written again
+func (d *deduper) flush() {
+	d.mux.Lock()
+	var summaries []*LogRecord
+	for k, e := range d.entries {
+		e.timer.Stop()
+		delete(d.entries, k)
+		if e.repeated > 0 {
+			summaries = append(summaries, e.summary())
+		}
+	}
+	d.mux.Unlock()
+
+	for _, summary := range summaries {
+		d.logger.writeSummary(d.sink, summary)
+	}
+}
+
+// flushDedup writes the summaries of the pending repetitions of the default
+// output and of the sinks
+func (l *logging) flushDedup() {
+	l.mux.Lock()
+	dedup := l.dedup
+	sinks := l.sinks
+	l.mux.Unlock()
+
+	if dedup != nil {
+		dedup.flush()
+	}
+
+	for _, s := range sinks {
+		if s.dedup != nil {
+			s.dedup.flush()
+		}
+	}
+}
+
+// deduplicate reports whether record is written to s, the summaries which
+// must be written before it are added to the batch
+func (b *batch) deduplicate(d *deduper, s *sink, formatter Formatter, record *LogRecord) bool {
+	if d == nil {
+		return true
+	}
+
+	if !b.keyed {
+		b.key = dedupKey{
+			level:   record.logLevel,
+			module:  record.module,
+			message: record.message(),
+			file:    record.file,
+			line:    record.line,
+		}
+		b.keyed = true
+	}
+
+	ok, summaries := d.check(b.key, record)
+	for _, summary := range summaries {
+		b.addSummary(s, formatter, summary)
+	}
+
+	return ok
+}
+
+// writeSummary writes the summary of an expired repetition to s
+func (l *logging) writeSummary(s *sink, summary *LogRecord) {
+	l.mux.Lock()
+	formatter := l.Formater
+	async := l.async
+	l.mux.Unlock()
+
+	if s != nil && s.Formatter != nil {
+		formatter = s.Formatter
+	}
+
+	b := &batch{level: summary.logLevel}
+	b.addSummary(s, formatter, summary)
+
+	if async != nil {
+		async.enqueue(b)
+		return
+	}
+
+	l.writeBatch(b)
+}
+
+// EnableDedup collapses the identical records written to the default
+// output, the records are identical when their module, level, message and
+// caller are the same. The sinks are configured with Sink.Dedup
+func (l *logging) EnableDedup(config DedupConfig) {
+	l.mux.Lock()
+	old := l.dedup
+	l.dedup = newDeduper(l, nil, config)
+	l.mux.Unlock()
+
+	if old != nil {
+		old.flush()
+	}
+}
+
+// DisableDedup writes every record to the default output again, the
+// summaries of the pending repetitions are written first
+func (l *logging) DisableDedup() {
+	l.mux.Lock()
+	old := l.dedup
+	l.dedup = nil
+	l.mux.Unlock()
+
+	if old != nil {
+		old.flush()
+	}
+}
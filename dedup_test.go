@@ -0,0 +1,150 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer written by the dedup timers
+type syncBuffer struct {
+	mux sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	return b.buf.String()
+}
+
+func TestDedupConsecutive(t *testing.T) {
+	buf := &syncBuffer{}
+
+	logging := NewLogging("test", INFO_LEVEL, 4)
+	logging.SetOutPut(buf)
+	logging.EnableDedup(DedupConfig{Interval: time.Hour})
+
+	for i := 0; i < 5; i++ {
+		logging.Error("connection refused")
+	}
+	logging.Info("recovered")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Errorf("expected 3 lines, got %q", lines)
+		return
+	}
+
+	expected := []string{"connection refused", "last message repeated 4 times", "recovered"}
+	for i := range expected {
+		if !strings.Contains(lines[i], expected[i]) {
+			t.Errorf("expected %s in %s", expected[i], lines[i])
+		}
+	}
+
+	// the summary has the level and the caller of the collapsed record
+	if !strings.Contains(lines[1], "Error") || strings.Split(lines[0], " ")[2] != strings.Split(lines[1], " ")[2] {
+		t.Errorf("unexpected summary %s", lines[1])
+	}
+}
+
+func TestDedupWindowed(t *testing.T) {
+	buf := &syncBuffer{}
+
+	logging := NewLogging("test", INFO_LEVEL, 4)
+	logging.SetOutPut(&bytes.Buffer{})
+	logging.AddSink(Sink{
+		Output: &WriterOutput{buf},
+		Dedup:  &DedupConfig{Interval: 50 * time.Millisecond, Windowed: true},
+	})
+
+	for i := 0; i < 3; i++ {
+		logging.Warn("disk almost full")
+		logging.Module("db").Warn("disk almost full")
+	}
+
+	if n := strings.Count(buf.String(), "disk almost full"); n != 2 {
+		t.Errorf("expected 2 records, got %d", n)
+		return
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for strings.Count(buf.String(), "last message repeated 2 times") != 2 {
+		if time.Now().After(deadline) {
+			t.Errorf("the summaries are missing in %s", buf.String())
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// the repetition is over, the record is written again
+	logging.Warn("disk almost full")
+	if n := strings.Count(buf.String(), "disk almost full"); n != 3 {
+		t.Errorf("expected 3 records, got %d", n)
+	}
+}
+
+func TestDedupFlush(t *testing.T) {
+	buf := &syncBuffer{}
+
+	logging := NewLogging("test", INFO_LEVEL, 4)
+	logging.SetOutPut(buf)
+	logging.EnableDedup(DedupConfig{Interval: time.Hour})
+
+	for i := 0; i < 3; i++ {
+		logging.Error("connection refused")
+	}
+	logging.Flush()
+
+	if !strings.Contains(buf.String(), "last message repeated 2 times") {
+		t.Errorf("the summary is not written by Flush in %s", buf.String())
+		return
+	}
+
+	for i := 0; i < 4; i++ {
+		logging.Error("connection refused")
+	}
+	logging.DisableDedup()
+
+	if strings.Count(buf.String(), "connection refused") != 2 || !strings.Contains(buf.String(), "last message repeated 3 times") {
+		t.Errorf("the summary is not written by DisableDedup in %s", buf.String())
+	}
+}
+
+func TestDedupSkipsStack(t *testing.T) {
+	buf := &syncBuffer{}
+
+	logging := NewLogging("test", INFO_LEVEL, 4)
+	logging.SetOutPut(buf)
+	logging.EnableStack(ERROR_LEVEL)
+	logging.EnableDedup(DedupConfig{Interval: time.Hour})
+	defer logging.DisableDedup()
+
+	records := []*LogRecord{
+		{logger: logging, logLevel: ERROR_LEVEL, args: []interface{}{"connection refused"}},
+		{logger: logging, logLevel: ERROR_LEVEL, args: []interface{}{"connection refused"}},
+	}
+	for _, record := range records {
+		logging.dispatch(record)
+	}
+
+	if records[0].stack == nil {
+		t.Error("no stack captured for the written record")
+	}
+
+	// the duplicate is dropped, its stack is never written
+	if records[1].stack != nil {
+		t.Error("stack captured for a dropped record")
+	}
+}
@@ -0,0 +1,92 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// Field is a key/value pair attached to a log record
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Fields is a set of key/value pairs, it is used by WithFields
+type Fields map[string]interface{}
+
+// badKey is used when With receives a value without a matching key
+const badKey = "!BADKEY"
+
+// toFields converts alternating keys and values into fields
+func toFields(keyValues []interface{}) []Field {
+	fields := make([]Field, 0, (len(keyValues)+1)/2)
+
+	for i := 0; i < len(keyValues); i += 2 {
+		if i+1 == len(keyValues) {
+			fields = append(fields, Field{Key: badKey, Value: keyValues[i]})
+			break
+		}
+
+		key, ok := keyValues[i].(string)
+		if !ok {
+			key = fmt.Sprint(keyValues[i])
+		}
+
+		fields = append(fields, Field{Key: key, Value: keyValues[i+1]})
+	}
+
+	return fields
+}
+
+// toSlice returns the fields sorted by key, so the output is stable
+func (f Fields) toSlice() []Field {
+	keys := make([]string, 0, len(f))
+	for key := range f {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fields := make([]Field, 0, len(keys))
+	for _, key := range keys {
+		fields = append(fields, Field{Key: key, Value: f[key]})
+	}
+
+	return fields
+}
+
+// mergeFields returns a new slice holding parent extended by extra, a key
+// of extra replaces the value of the same key in parent. parent is never
+// modified.
+func mergeFields(parent, extra []Field) []Field {
+	merged := make([]Field, len(parent), len(parent)+len(extra))
+	copy(merged, parent)
+
+	for _, field := range extra {
+		replaced := false
+		for i := range merged {
+			if merged[i].Key == field.Key {
+				merged[i].Value = field.Value
+				replaced = true
+				break
+			}
+		}
+
+		if !replaced {
+			merged = append(merged, field)
+		}
+	}
+
+	return merged
+}
+
+// writeFields writes fields as " key=value" pairs, the values are quoted
+// the same way as LogfmtFormatter does
+func writeFields(buf *bytes.Buffer, fields []Field) {
+	for _, field := range fields {
+		buf.WriteString(" ")
+		writeLogfmtKey(buf, field.Key)
+		buf.WriteString("=")
+		writeLogfmtValue(buf, field.Value)
+	}
+}
@@ -0,0 +1,65 @@
+package log
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWithFields(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logging := NewLogging("test", INFO_LEVEL, 2)
+	logging.SetOutPut(buf)
+
+	logging.With("request", "abc", "user", 42).Info("Test Message")
+	if !strings.Contains(buf.String(), "Test Message request=abc user=42\n") {
+		t.Errorf("unexpected output %q", buf.String())
+		return
+	}
+
+	buf.Reset()
+	logging.WithFields(Fields{"b": 2, "a": 1}).Warnf("Test %s", "Message")
+	if !strings.Contains(buf.String(), "Warn msg: Test Message a=1 b=2\n") {
+		t.Errorf("unexpected output %q", buf.String())
+		return
+	}
+}
+
+func TestChildFields(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logging := NewLogging("test", INFO_LEVEL, 2)
+	logging.SetOutPut(buf)
+
+	parent := logging.With("request", "abc")
+	child := parent.With("request", "def", "duration", 1.5)
+
+	if !reflect.DeepEqual(parent.Fields(), []Field{{Key: "request", Value: "abc"}}) {
+		t.Error("parent fields are modified by child")
+		return
+	}
+
+	child.Info("child")
+	if !strings.Contains(buf.String(), "child request=def duration=1.5\n") {
+		t.Errorf("unexpected output %q", buf.String())
+		return
+	}
+
+	buf.Reset()
+	parent.Info("parent")
+	if !strings.Contains(buf.String(), "parent request=abc\n") {
+		t.Errorf("unexpected output %q", buf.String())
+		return
+	}
+}
+
+func TestBadKey(t *testing.T) {
+	fields := toFields([]interface{}{"a", 1, 2, "b", "c"})
+
+	expected := []Field{{Key: "a", Value: 1}, {Key: "2", Value: "b"}, {Key: badKey, Value: "c"}}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("unexpected fields %v", fields)
+	}
+}
@@ -2,46 +2,53 @@ package log
 
 import (
 	"bytes"
-	"fmt"
-	"runtime"
 	"strconv"
 )
 
 type Formatter func(logRecord *LogRecord) *bytes.Buffer
 
 func DefaultFormater(logRecord *LogRecord) *bytes.Buffer {
-	var (
-		s string
-	)
-
-	if len(logRecord.format) == 0 {
-		s = fmt.Sprint(logRecord.args...)
-	} else {
-		s = fmt.Sprintf(logRecord.format, logRecord.args...)
-	}
+	caller, line := logRecord.caller()
+	return formatText(logRecord, caller, line, "[ ", " ] ")
+}
 
-	time := CacheTime()
+// formatText prints the record as a line of text, the module is enclosed
+// between open and close. The caller is resolved by the formatters, so its
+// depth is the same for all of them
+func formatText(logRecord *LogRecord, caller string, line int, open, close string) *bytes.Buffer {
+	time := formatCacheTime(logRecord.timestamp())
 
-	_, caller, line, _ := runtime.Caller(logRecord.callerLevel)
 	buf := pool.Get()
 	buf.Reset()
 
 	if len(logRecord.module) != 0 {
-		buf.WriteString("[ ")
+		buf.WriteString(open)
 		buf.WriteString(logRecord.module)
-		buf.WriteString(" ] ")
+		buf.WriteString(close)
 	}
 
 	buf.WriteString(time)
 	buf.WriteString(" ")
-	buf.WriteString(caller)
-	buf.WriteString(":")
-	buf.WriteString(strconv.Itoa(line))
-	buf.WriteString(" ")
+	if logRecord.enableCaller {
+		buf.WriteString(caller)
+		buf.WriteString(":")
+		buf.WriteString(strconv.Itoa(line))
+		buf.WriteString(" ")
+		if len(logRecord.function) != 0 {
+			buf.WriteString(logRecord.function)
+			buf.WriteString(" ")
+		}
+	}
 	buf.WriteString(logRecord.logLevel.String())
 	buf.WriteString(" msg: ")
-	buf.WriteString(s)
+	buf.WriteString(logRecord.message())
+	if logRecord.err != nil {
+		buf.WriteString(" error=")
+		writeLogfmtString(buf, logRecord.err.Error())
+	}
+	writeFields(buf, logRecord.fields)
 	buf.WriteString("\n")
+	writeStack(buf, logRecord.stack)
 
 	return buf
 }
@@ -2,10 +2,8 @@ package log
 
 import (
 	"bytes"
-	"fmt"
+	"context"
 	"io"
-	"runtime"
-	"strconv"
 )
 
 var (
@@ -13,46 +11,22 @@ var (
 )
 
 func globalLogFormatter(logRecord *LogRecord) *bytes.Buffer {
-	var (
-		s string
-	)
-
-	if len(logRecord.format) == 0 {
-		s = fmt.Sprint(logRecord.args...)
-	} else {
-		s = fmt.Sprintf(logRecord.format, logRecord.args...)
-	}
-
-	time := CacheTime()
-	_, caller, line, _ := runtime.Caller(logRecord.callerLevel)
-
-	buf := pool.Get()
-	buf.Reset()
-
-	if len(logRecord.module) != 0 {
-		buf.WriteString("[")
-		buf.WriteString(logRecord.module)
-		buf.WriteString("] ")
-	}
-
-	buf.WriteString(time)
-	buf.WriteString(" ")
-	buf.WriteString(caller)
-	buf.WriteString(":")
-	buf.WriteString(strconv.Itoa(line))
-	buf.WriteString(" ")
-	buf.WriteString(logRecord.logLevel.String())
-	buf.WriteString(" msg: ")
-	buf.WriteString(s)
-	buf.WriteString("\n")
-
-	return buf
+	caller, line := logRecord.caller()
+	return formatText(logRecord, caller, line, "[", "] ")
 }
 
 func SetLogLevel(level LoggingLevel) {
 	logger.SetLevel(level)
 }
 
+func SetModuleLevel(pattern string, level LoggingLevel) error {
+	return logger.SetModuleLevel(pattern, level)
+}
+
+func RemoveModuleLevel(pattern string) {
+	logger.RemoveModuleLevel(pattern)
+}
+
 func Trace(args ...interface{}) {
 	logger.Trace(args...)
 }
@@ -101,6 +75,46 @@ func Fatalf(format string, args ...interface{}) {
 	logger.Fatalf(format, args...)
 }
 
+func TraceContext(ctx context.Context, args ...interface{}) {
+	logger.TraceContext(ctx, args...)
+}
+
+func DebugContext(ctx context.Context, args ...interface{}) {
+	logger.DebugContext(ctx, args...)
+}
+
+func InfoContext(ctx context.Context, args ...interface{}) {
+	logger.InfoContext(ctx, args...)
+}
+
+func WarnContext(ctx context.Context, args ...interface{}) {
+	logger.WarnContext(ctx, args...)
+}
+
+func ErrorContext(ctx context.Context, args ...interface{}) {
+	logger.ErrorContext(ctx, args...)
+}
+
+func TracefContext(ctx context.Context, format string, args ...interface{}) {
+	logger.TracefContext(ctx, format, args...)
+}
+
+func DebugfContext(ctx context.Context, format string, args ...interface{}) {
+	logger.DebugfContext(ctx, format, args...)
+}
+
+func InfofContext(ctx context.Context, format string, args ...interface{}) {
+	logger.InfofContext(ctx, format, args...)
+}
+
+func WarnfContext(ctx context.Context, format string, args ...interface{}) {
+	logger.WarnfContext(ctx, format, args...)
+}
+
+func ErrorfContext(ctx context.Context, format string, args ...interface{}) {
+	logger.ErrorfContext(ctx, format, args...)
+}
+
 func Println(args ...interface{}) {
 	logger.Info(args...)
 }
@@ -113,6 +127,18 @@ func Fatalln(args ...interface{}) {
 	logger.Fatal(args...)
 }
 
+func SetFormatter(formatter Formatter) {
+	logger.SetFormatter(formatter)
+}
+
+func SetCallerOptions(opts CallerOptions) {
+	logger.SetCallerOptions(opts)
+}
+
+func EnableCaller(enable bool) {
+	logger.EnableCaller(enable)
+}
+
 func SetOutPut(w io.Writer) {
 	logger.SetOutPut(w)
 }
@@ -121,10 +147,58 @@ func Module(module string) *LogRecord {
 	return logger.Module(module)
 }
 
+func With(keyValues ...interface{}) *LogRecord {
+	return logger.With(keyValues...)
+}
+
+func WithFields(fields Fields) *LogRecord {
+	return logger.WithFields(fields)
+}
+
+func WithError(err error) *LogRecord {
+	return logger.WithError(err)
+}
+
+func WithStack() *LogRecord {
+	return logger.WithStack()
+}
+
+func EnableStack(level LoggingLevel) {
+	logger.EnableStack(level)
+}
+
+func DisableStack() {
+	logger.DisableStack()
+}
+
+func AddHook(h Hook, phase HookPhase) {
+	logger.AddHook(h, phase)
+}
+
+func ClearHooks() {
+	logger.ClearHooks()
+}
+
+func Ctx(ctx context.Context) *LogRecord {
+	return logger.Ctx(ctx)
+}
+
 func CallLevel(level int) *LogRecord {
 	return logger.Caller(level)
 }
 
+func EnableAsync(config AsyncConfig) {
+	logger.EnableAsync(config)
+}
+
+func SetSampler(s *Sampler) {
+	logger.SetSampler(s)
+}
+
+func Flush() {
+	logger.Flush()
+}
+
 func Start() {
 	logger.Start()
 }
@@ -0,0 +1,31 @@
+package log
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestHelperPackage(t *testing.T) {
+	h := &helpers{
+		packages:  map[string]struct{}{packagePath: {}, "github.com/org/app/logutil": {}},
+		functions: map[string]struct{}{"github.com/org/app/db.logQuery": {}},
+	}
+
+	cases := []struct {
+		frame    runtime.Frame
+		expected bool
+	}{
+		{runtime.Frame{Function: "github.com/org/app/logutil.Errorf", File: "/src/logutil/log.go"}, true},
+		{runtime.Frame{Function: "github.com/org/app/db.logQuery.func1", File: "/src/db/db.go"}, true},
+		{runtime.Frame{Function: "github.com/org/app/db.Query", File: "/src/db/db.go"}, false},
+		{runtime.Frame{Function: packagePath + ".(*logging).Info", File: "/src/log/logging.go"}, true},
+		{runtime.Frame{Function: packagePath + ".TestHelperPackage", File: "/src/log/helper_test.go"}, true},
+		{runtime.Frame{Function: packagePath + "_test.TestCallerFrameSkipping", File: "/src/log/callsite_test.go"}, false},
+	}
+
+	for _, c := range cases {
+		if h.isHelper(&c.frame) != c.expected {
+			t.Errorf("expected %v for %s", c.expected, c.frame.Function)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package log
+
+import (
+	"fmt"
+	"time"
+)
+
+// Hook is called for the records whose level is in Levels, e.g. to count
+// the errors or to forward them to an alerting system. The error returned by
+// Fire is passed to the error handler of the logging, it does not stop the
+// record from being written. The dedup summaries do not fire the hooks, every
+// collapsed record already fired them
+type Hook interface {
+	Levels() []LoggingLevel
+	Fire(entry *Entry) error
+}
+
+// HookPhase decides when a hook is fired
+type HookPhase int
+
+const (
+	// before the record is formatted and written
+	HOOK_BEFORE_WRITE HookPhase = iota
+	// once the record is written, by the async writer in async mode
+	HOOK_AFTER_WRITE
+)
+
+// AllLevels is the levels of a hook firing on every record
+var AllLevels = []LoggingLevel{
+	TRACE_LEVEL,
+	DEBUG_LEVEL,
+	INFO_LEVEL,
+	WARN_LEVEL,
+	ERROR_LEVEL,
+	FATAL_LEVEL,
+}
+
+type hook struct {
+	Hook
+	phase HookPhase
+	// a bit for every level of Levels
+	levels uint
+}
+
+func (h *hook) firesOn(level LoggingLevel) bool {
+	return h.levels&(1<<uint(level)) != 0
+}
+
+// Entry is the read-only view of a record given to the hooks
+type Entry struct {
+	record  *LogRecord
+	message string
+}
+
+func newEntry(record *LogRecord) *Entry {
+	return &Entry{
+		record:  record,
+		message: record.message(),
+	}
+}
+
+// Time returns the time the record is printed with
+func (e *Entry) Time() time.Time {
+	return e.record.timestamp()
+}
+
+func (e *Entry) Level() LoggingLevel {
+	return e.record.logLevel
+}
+
+func (e *Entry) Module() string {
+	return e.record.module
+}
+
+// Caller returns the file and the line the record is logged at, they are
+// empty when the caller is disabled
+func (e *Entry) Caller() (string, int) {
+	if !e.record.enableCaller {
+		return "", 0
+	}
+
+	return e.record.file, e.record.line
+}
+
+func (e *Entry) Message() string {
+	return e.message
+}
+
+// Fields returns a copy of the fields attached to the record
+func (e *Entry) Fields() []Field {
+	return e.record.Fields()
+}
+
+// Err returns the error attached by WithError
+func (e *Entry) Err() error {
+	return e.record.err
+}
+
+// AddHook registers h, it is fired in phase for the records at the levels
+// returned by h.Levels, which is only called once
+func (l *logging) AddHook(h Hook, phase HookPhase) {
+	nh := &hook{Hook: h, phase: phase}
+	for _, level := range h.Levels() {
+		nh.levels |= 1 << uint(level)
+	}
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	l.hooks = append(l.hooks, nh)
+}
+
+// ClearHooks removes all the hooks
+func (l *logging) ClearHooks() {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	l.hooks = nil
+}
+
+// hooksOf returns the hooks fired in phase for level
+func hooksOf(hooks []*hook, phase HookPhase, level LoggingLevel) []*hook {
+	var matched []*hook
+	for _, h := range hooks {
+		if h.phase == phase && h.firesOn(level) {
+			matched = append(matched, h)
+		}
+	}
+
+	return matched
+}
+
+// fireHooks fires every hook with entry, the errors and the panics are
+// reported to the error handler
+func (l *logging) fireHooks(hooks []*hook, entry *Entry) {
+	for _, h := range hooks {
+		if err := fireHook(h, entry); err != nil {
+			l.handleError(err)
+		}
+	}
+}
+
+func fireHook(h *hook, entry *Entry) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("hook panic: %v", r)
+		}
+	}()
+
+	if err := h.Fire(entry); err != nil {
+		return fmt.Errorf("hook: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,204 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type captureHook struct {
+	levels []LoggingLevel
+	err    error
+	// called when the hook is fired
+	fire func(entry *Entry)
+
+	mux     sync.Mutex
+	entries []*Entry
+}
+
+func (h *captureHook) Levels() []LoggingLevel {
+	return h.levels
+}
+
+func (h *captureHook) Fire(entry *Entry) error {
+	if h.fire != nil {
+		h.fire(entry)
+	}
+
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	h.entries = append(h.entries, entry)
+	return h.err
+}
+
+func (h *captureHook) captured() []*Entry {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	return h.entries
+}
+
+func TestHook(t *testing.T) {
+	buf := &bytes.Buffer{}
+	hook := &captureHook{levels: []LoggingLevel{WARN_LEVEL, ERROR_LEVEL}}
+
+	logging := NewLogging("test", DEBUG_LEVEL, 4)
+	logging.SetOutPut(buf)
+	logging.AddHook(hook, HOOK_BEFORE_WRITE)
+
+	logging.Info("ignored")
+	logging.Module("db").With("id", 1).WithError(errors.New("timeout")).Errorf("query %s failed", "q1")
+
+	entries := hook.captured()
+	if len(entries) != 1 {
+		t.Errorf("expected 1 entry, got %d", len(entries))
+		return
+	}
+
+	entry := entries[0]
+	if entry.Level() != ERROR_LEVEL || entry.Module() != "db" || entry.Message() != "query q1 failed" {
+		t.Errorf("unexpected entry %s %s %s", entry.Level(), entry.Module(), entry.Message())
+	}
+
+	if file, line := entry.Caller(); file == "" || line == 0 {
+		t.Errorf("unexpected caller %s:%d", file, line)
+	}
+
+	if entry.Err() == nil || entry.Err().Error() != "timeout" {
+		t.Errorf("unexpected error %v", entry.Err())
+	}
+
+	fields := entry.Fields()
+	if len(fields) != 1 || fields[0].Key != "id" {
+		t.Errorf("unexpected fields %v", fields)
+		return
+	}
+
+	// the entry is read-only
+	fields[0].Key = "changed"
+	if entry.Fields()[0].Key != "id" {
+		t.Error("the fields of the entry were modified")
+	}
+
+	logging.ClearHooks()
+	logging.Error("not captured")
+	if len(hook.captured()) != 1 {
+		t.Errorf("expected 1 entry after ClearHooks, got %d", len(hook.captured()))
+	}
+}
+
+func TestHookPhase(t *testing.T) {
+	buf := &syncBuffer{}
+	var before, after string
+
+	logging := NewLogging("test", INFO_LEVEL, 4)
+	logging.SetOutPut(buf)
+	logging.EnableAsync(AsyncConfig{})
+	logging.AddHook(&captureHook{
+		levels: AllLevels,
+		fire:   func(entry *Entry) { before = buf.String() },
+	}, HOOK_BEFORE_WRITE)
+	logging.AddHook(&captureHook{
+		levels: AllLevels,
+		fire:   func(entry *Entry) { after = buf.String() },
+	}, HOOK_AFTER_WRITE)
+
+	logging.Info("hello")
+	logging.Flush()
+
+	if strings.Contains(before, "hello") {
+		t.Errorf("the record is written before the hook %q", before)
+	}
+
+	if !strings.Contains(after, "hello") {
+		t.Errorf("the record is not written before the hook %q", after)
+	}
+}
+
+func TestHookError(t *testing.T) {
+	buf := &bytes.Buffer{}
+	var errs []error
+
+	logging := NewLogging("test", INFO_LEVEL, 4)
+	logging.SetOutPut(buf)
+	logging.SetErrorHandler(func(err error) {
+		errs = append(errs, err)
+	})
+	logging.AddHook(&captureHook{levels: AllLevels, err: errors.New("alerting is down")}, HOOK_BEFORE_WRITE)
+	logging.AddHook(&captureHook{
+		levels: AllLevels,
+		fire:   func(entry *Entry) { panic("broken hook") },
+	}, HOOK_AFTER_WRITE)
+
+	logging.Error("failed")
+
+	if !strings.Contains(buf.String(), "failed") {
+		t.Errorf("the record is not written %q", buf.String())
+	}
+
+	if len(errs) != 2 || errs[0].Error() != "hook: alerting is down" || errs[1].Error() != "hook panic: broken hook" {
+		t.Errorf("unexpected errors %v", errs)
+	}
+}
+
+func TestHookTime(t *testing.T) {
+	buf := &bytes.Buffer{}
+	hook := &captureHook{levels: AllLevels}
+
+	formatter, err := PatternFormatter("%time{RFC3339Nano} %msg")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	logging := NewLogging("test", INFO_LEVEL, 4)
+	logging.SetOutPut(buf)
+	logging.SetFormatter(formatter)
+	logging.AddHook(hook, HOOK_BEFORE_WRITE)
+	logging.Info("hello")
+
+	entries := hook.captured()
+	if len(entries) != 1 {
+		t.Errorf("expected 1 entry, got %d", len(entries))
+		return
+	}
+
+	expected := entries[0].Time().Format(time.RFC3339Nano) + " hello\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestHookDedup(t *testing.T) {
+	buf := &syncBuffer{}
+	before := &captureHook{levels: AllLevels}
+	after := &captureHook{levels: AllLevels}
+
+	logging := NewLogging("test", INFO_LEVEL, 4)
+	logging.SetOutPut(buf)
+	logging.EnableDedup(DedupConfig{Interval: time.Hour})
+	logging.AddHook(before, HOOK_BEFORE_WRITE)
+	logging.AddHook(after, HOOK_AFTER_WRITE)
+
+	for i := 0; i < 3; i++ {
+		logging.Error("connection refused")
+	}
+	logging.Warn("slow query")
+	logging.DisableDedup()
+
+	if !strings.Contains(buf.String(), "last message repeated 2 times") {
+		t.Errorf("no summary in %s", buf.String())
+		return
+	}
+
+	// every record fires the hooks once, the summary does not
+	for _, hook := range []*captureHook{before, after} {
+		if len(hook.captured()) != 4 {
+			t.Errorf("expected 4 entries, got %d", len(hook.captured()))
+		}
+	}
+}
@@ -1,5 +1,5 @@
 package log
 
 func init() {
-	logger = NewLoggingWithFormater(INFO_LEVEL, 5, globalLogFormatter)
+	logger = NewLoggingWithFormater(INFO_LEVEL, 0, globalLogFormatter)
 }
@@ -0,0 +1,314 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// JSONFormatterOptions configures the key names and the time layout used by
+// JSONFormatter, empty values fall back to the defaults
+type JSONFormatterOptions struct {
+	TimeKey   string `json:"timeKey"`
+	LevelKey  string `json:"levelKey"`
+	ModuleKey string `json:"moduleKey"`
+	CallerKey string `json:"callerKey"`
+	// the function of the caller, see CallerOptions.Function
+	FunctionKey string `json:"functionKey"`
+	MessageKey  string `json:"messageKey"`
+	ErrorKey    string `json:"errorKey"`
+	// the stack trace, an array of {"func", "file", "line"} objects
+	StackKey string `json:"stackKey"`
+	// the fields are nested under FieldsKey when it is set, otherwise
+	// every field is a top level key, the fields named like one of the keys
+	// above are prefixed with "fields." so the keys stay unique
+	FieldsKey  string `json:"fieldsKey"`
+	TimeLayout string `json:"timeLayout"`
+}
+
+const defaultJSONTimeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// clashingFieldPrefix is prepended to the top level fields whose key is
+// already used by the record
+const clashingFieldPrefix = "fields."
+
+func (o *JSONFormatterOptions) setDefaults() {
+	if o.TimeKey == "" {
+		o.TimeKey = "time"
+	}
+
+	if o.LevelKey == "" {
+		o.LevelKey = "level"
+	}
+
+	if o.ModuleKey == "" {
+		o.ModuleKey = "module"
+	}
+
+	if o.CallerKey == "" {
+		o.CallerKey = "caller"
+	}
+
+	if o.FunctionKey == "" {
+		o.FunctionKey = "func"
+	}
+
+	if o.MessageKey == "" {
+		o.MessageKey = "msg"
+	}
+
+	if o.ErrorKey == "" {
+		o.ErrorKey = "error"
+	}
+
+	if o.StackKey == "" {
+		o.StackKey = "stack"
+	}
+
+	if o.TimeLayout == "" {
+		o.TimeLayout = defaultJSONTimeLayout
+	}
+}
+
+// reservedKeys returns the keys the fields must not use when they are top
+// level keys
+func (o *JSONFormatterOptions) reservedKeys() map[string]struct{} {
+	keys := map[string]struct{}{}
+	for _, key := range []string{
+		o.TimeKey, o.LevelKey, o.ModuleKey, o.CallerKey, o.FunctionKey,
+		o.MessageKey, o.ErrorKey, o.StackKey,
+	} {
+		keys[key] = struct{}{}
+	}
+
+	return keys
+}
+
+// JSONFormatter returns a Formatter which prints every record as a single
+// line JSON object
+func JSONFormatter(opts JSONFormatterOptions) Formatter {
+	opts.setDefaults()
+	reserved := opts.reservedKeys()
+
+	return func(logRecord *LogRecord) *bytes.Buffer {
+		caller, line := logRecord.caller()
+
+		buf := pool.Get()
+		buf.Reset()
+
+		buf.WriteString("{")
+		writeJSONString(buf, opts.TimeKey)
+		buf.WriteString(":")
+		writeJSONString(buf, logRecord.timestamp().Format(opts.TimeLayout))
+
+		buf.WriteString(",")
+		writeJSONString(buf, opts.LevelKey)
+		buf.WriteString(":")
+		writeJSONString(buf, logRecord.logLevel.String())
+
+		if len(logRecord.module) != 0 {
+			buf.WriteString(",")
+			writeJSONString(buf, opts.ModuleKey)
+			buf.WriteString(":")
+			writeJSONString(buf, logRecord.module)
+		}
+
+		if logRecord.enableCaller {
+			buf.WriteString(",")
+			writeJSONString(buf, opts.CallerKey)
+			buf.WriteString(":")
+			writeJSONString(buf, caller+":"+strconv.Itoa(line))
+
+			if len(logRecord.function) != 0 {
+				buf.WriteString(",")
+				writeJSONString(buf, opts.FunctionKey)
+				buf.WriteString(":")
+				writeJSONString(buf, logRecord.function)
+			}
+		}
+
+		buf.WriteString(",")
+		writeJSONString(buf, opts.MessageKey)
+		buf.WriteString(":")
+		writeJSONString(buf, logRecord.message())
+
+		if logRecord.err != nil {
+			buf.WriteString(",")
+			writeJSONString(buf, opts.ErrorKey)
+			buf.WriteString(":")
+			writeJSONString(buf, logRecord.err.Error())
+		}
+
+		if len(logRecord.fields) != 0 {
+			if opts.FieldsKey != "" {
+				buf.WriteString(",")
+				writeJSONString(buf, opts.FieldsKey)
+				buf.WriteString(":{")
+				writeJSONFields(buf, logRecord.fields, false, nil)
+				buf.WriteString("}")
+			} else {
+				writeJSONFields(buf, logRecord.fields, true, reserved)
+			}
+		}
+
+		if len(logRecord.stack) != 0 {
+			buf.WriteString(",")
+			writeJSONString(buf, opts.StackKey)
+			buf.WriteString(":[")
+			for i, frame := range logRecord.stack {
+				if i > 0 {
+					buf.WriteString(",")
+				}
+				buf.WriteString(`{"func":`)
+				writeJSONString(buf, frame.Function)
+				buf.WriteString(`,"file":`)
+				writeJSONString(buf, frame.File)
+				buf.WriteString(`,"line":`)
+				buf.WriteString(strconv.Itoa(frame.Line))
+				buf.WriteString("}")
+			}
+			buf.WriteString("]")
+		}
+
+		buf.WriteString("}\n")
+
+		return buf
+	}
+}
+
+// writeJSONFields writes the fields as object members, the keys found in
+// reserved are prefixed with clashingFieldPrefix
+func writeJSONFields(buf *bytes.Buffer, fields []Field, leadingComma bool, reserved map[string]struct{}) {
+	for i, field := range fields {
+		if i > 0 || leadingComma {
+			buf.WriteString(",")
+		}
+
+		if _, ok := reserved[field.Key]; ok {
+			writeJSONString(buf, clashingFieldPrefix+field.Key)
+		} else {
+			writeJSONString(buf, field.Key)
+		}
+		buf.WriteString(":")
+		writeJSONValue(buf, field.Value)
+	}
+}
+
+func writeJSONValue(buf *bytes.Buffer, value interface{}) {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteString("null")
+	case string:
+		writeJSONString(buf, v)
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case int:
+		buf.WriteString(strconv.Itoa(v))
+	case int32:
+		buf.WriteString(strconv.FormatInt(int64(v), 10))
+	case int64:
+		buf.WriteString(strconv.FormatInt(v, 10))
+	case uint:
+		buf.WriteString(strconv.FormatUint(uint64(v), 10))
+	case uint32:
+		buf.WriteString(strconv.FormatUint(uint64(v), 10))
+	case uint64:
+		buf.WriteString(strconv.FormatUint(v, 10))
+	case float32:
+		writeJSONFloat(buf, float64(v), 32)
+	case float64:
+		writeJSONFloat(buf, v, 64)
+	case time.Duration:
+		writeJSONString(buf, v.String())
+	case time.Time:
+		writeJSONString(buf, v.Format(time.RFC3339Nano))
+	case error:
+		writeJSONString(buf, v.Error())
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			writeJSONString(buf, fmt.Sprint(v))
+			return
+		}
+
+		buf.Write(data)
+	}
+}
+
+// writeJSONFloat writes NaN and infinities as strings, they are not valid
+// JSON numbers
+func writeJSONFloat(buf *bytes.Buffer, f float64, bitSize int) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		writeJSONString(buf, strconv.FormatFloat(f, 'g', -1, bitSize))
+		return
+	}
+
+	buf.WriteString(strconv.FormatFloat(f, 'g', -1, bitSize))
+}
+
+const hex = "0123456789abcdef"
+
+// writeJSONString writes s as a quoted JSON string, invalid UTF-8 is
+// replaced by U+FFFD
+func writeJSONString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' {
+				i++
+				continue
+			}
+
+			buf.WriteString(s[start:i])
+			switch b {
+			case '"', '\\':
+				buf.WriteByte('\\')
+				buf.WriteByte(b)
+			case '\n':
+				buf.WriteString("\\n")
+			case '\r':
+				buf.WriteString("\\r")
+			case '\t':
+				buf.WriteString("\\t")
+			default:
+				buf.WriteString("\\u00")
+				buf.WriteByte(hex[b>>4])
+				buf.WriteByte(hex[b&0xf])
+			}
+
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf.WriteString(s[start:i])
+			buf.WriteString("\\ufffd")
+			i += size
+			start = i
+			continue
+		}
+
+		// U+2028 and U+2029 break javascript parsers
+		if r == '\u2028' || r == '\u2029' {
+			buf.WriteString(s[start:i])
+			buf.WriteString("\\u202")
+			buf.WriteByte(hex[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+
+		i += size
+	}
+
+	buf.WriteString(s[start:])
+	buf.WriteByte('"')
+}
@@ -0,0 +1,136 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestJSONFormatter(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logging := NewLoggingWithFormater(INFO_LEVEL, 2, JSONFormatter(JSONFormatterOptions{}))
+	logging.SetOutPut(buf)
+
+	logging.Module("db").With("user", 42, "ok", true).WithError(errors.New("timeout")).Warn("quote \" and\nnewline")
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Errorf("invalid json %q: %v", buf.String(), err)
+		return
+	}
+
+	expected := map[string]interface{}{
+		"level":  "Warn",
+		"module": "db",
+		"msg":    "quote \" and\nnewline",
+		"error":  "timeout",
+		"user":   float64(42),
+		"ok":     true,
+	}
+
+	for key, value := range expected {
+		if m[key] != value {
+			t.Errorf("unexpected value of %s: %v", key, m[key])
+		}
+	}
+
+	if _, ok := m["time"]; !ok {
+		t.Error("time is missing")
+	}
+
+	if _, ok := m["caller"]; !ok {
+		t.Error("caller is missing")
+	}
+
+	if strings.Count(buf.String(), "\n") != 1 {
+		t.Error("json record should be a single line")
+	}
+}
+
+func TestJSONFormatterOptions(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logging := NewLoggingWithFormater(INFO_LEVEL, 4, JSONFormatter(JSONFormatterOptions{
+		TimeKey:    "ts",
+		MessageKey: "message",
+		FieldsKey:  "fields",
+		TimeLayout: "2006",
+	}))
+	logging.SetOutPut(buf)
+
+	logging.With("a", "b").Info("Test Message")
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Errorf("invalid json %q: %v", buf.String(), err)
+		return
+	}
+
+	if ts, _ := m["ts"].(string); len(ts) != 4 {
+		t.Errorf("unexpected time %v", m["ts"])
+	}
+
+	if m["message"] != "Test Message" {
+		t.Errorf("unexpected message %v", m["message"])
+	}
+
+	fields, _ := m["fields"].(map[string]interface{})
+	if fields["a"] != "b" {
+		t.Errorf("unexpected fields %v", m["fields"])
+	}
+}
+
+func TestJSONFormatterClashingFields(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logging := NewLoggingWithFormater(INFO_LEVEL, 4, JSONFormatter(JSONFormatterOptions{}))
+	logging.SetOutPut(buf)
+
+	logging.With("msg", "field", "level", 1, "user", 42).Info("message")
+
+	for _, key := range []string{`"msg":`, `"level":`} {
+		if strings.Count(buf.String(), key) != 1 {
+			t.Errorf("duplicate key %s in %q", key, buf.String())
+		}
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Errorf("invalid json %q: %v", buf.String(), err)
+		return
+	}
+
+	expected := map[string]interface{}{
+		"msg":          "message",
+		"level":        "Info",
+		"fields.msg":   "field",
+		"fields.level": float64(1),
+		"user":         float64(42),
+	}
+
+	for key, value := range expected {
+		if m[key] != value {
+			t.Errorf("unexpected value of %s: %v", key, m[key])
+		}
+	}
+}
+
+func TestWriteJSONString(t *testing.T) {
+	for _, s := range []string{"plain", "tab\there", "ctrl\x01", "back\\slash", "unicode 中文", "\u2028", "bad\xffutf8"} {
+		buf := &bytes.Buffer{}
+		writeJSONString(buf, s)
+
+		var decoded string
+		if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+			t.Errorf("invalid json string %q: %v", buf.String(), err)
+			continue
+		}
+
+		if decoded != strings.ToValidUTF8(s, "\ufffd") {
+			t.Errorf("expected %q, got %q", s, decoded)
+		}
+	}
+}
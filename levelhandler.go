@@ -0,0 +1,215 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// levelState is the JSON view of the levels of a logging
+type levelState struct {
+	Level   string            `json:"level"`
+	Modules map[string]string `json:"modules"`
+}
+
+type levelsResponse struct {
+	Global  levelState            `json:"global"`
+	Loggers map[string]levelState `json:"loggers"`
+}
+
+// levelRequest changes the level of the registered logger named Logger,
+// or of the global logger when Logger is empty. With Module the level of
+// the module pattern is changed instead, and an empty Level removes it. The
+// previous level is restored after TTL, e.g. "10m", when it is set
+type levelRequest struct {
+	Logger string `json:"logger"`
+	Module string `json:"module"`
+	Level  string `json:"level"`
+	TTL    string `json:"ttl"`
+}
+
+type levelKey struct {
+	logger *logging
+	module string
+}
+
+type levelRevert struct {
+	timer    *time.Timer
+	previous LoggingLevel
+	// false if the module had no level of its own
+	existed bool
+}
+
+var _ http.Handler = &LevelHandler{}
+
+// LevelHandler shows the levels of the global logger and of the registered
+// loggers on GET, and changes them on PUT or POST
+type LevelHandler struct {
+	mux     sync.Mutex
+	reverts map[levelKey]*levelRevert
+}
+
+func NewLevelHandler() *LevelHandler {
+	return &LevelHandler{
+		reverts: map[levelKey]*levelRevert{},
+	}
+}
+
+// stateOf returns the level of l and the effective level of every module
+// and module pattern it knows
+func stateOf(l *logging) levelState {
+	state := levelState{
+		Level:   l.GetLevel().String(),
+		Modules: map[string]string{},
+	}
+
+	for _, module := range l.knownModules() {
+		state.Modules[module] = l.levelOf(module).String()
+	}
+
+	return state
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func (h *LevelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.get(w)
+	case http.MethodPut, http.MethodPost:
+		h.set(w, r)
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		writeJSONError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method))
+	}
+}
+
+func (h *LevelHandler) get(w http.ResponseWriter) {
+	response := levelsResponse{
+		Global:  stateOf(logger),
+		Loggers: map[string]levelState{},
+	}
+
+	for _, name := range registeredNames() {
+		if l, ok := Lookup(name); ok {
+			response.Loggers[name] = stateOf(l)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *LevelHandler) set(w http.ResponseWriter, r *http.Request) {
+	var request levelRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %v", err))
+		return
+	}
+
+	var (
+		level  LoggingLevel
+		err    error
+		remove = request.Module != "" && request.Level == ""
+	)
+
+	if !remove {
+		if level, err = ParseLevel(request.Level); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	if request.Module != "" {
+		if err := validModulePattern(request.Module); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	var ttl time.Duration
+	if request.TTL != "" {
+		ttl, err = time.ParseDuration(request.TTL)
+		if err != nil || ttl <= 0 {
+			writeJSONError(w, http.StatusBadRequest, fmt.Errorf("invalid ttl %q", request.TTL))
+			return
+		}
+	}
+
+	l := logger
+	if request.Logger != "" {
+		var ok bool
+		if l, ok = Lookup(request.Logger); !ok {
+			writeJSONError(w, http.StatusNotFound, fmt.Errorf("unknown logger %q", request.Logger))
+			return
+		}
+	}
+
+	h.setLevel(levelKey{logger: l, module: request.Module}, level, remove, ttl)
+	writeJSON(w, http.StatusOK, stateOf(l))
+}
+
+func (h *LevelHandler) current(key levelKey) (LoggingLevel, bool) {
+	if key.module == "" {
+		return key.logger.GetLevel(), true
+	}
+
+	return key.logger.moduleLevel(key.module)
+}
+
+func (h *LevelHandler) apply(key levelKey, level LoggingLevel, exists bool) {
+	switch {
+	case key.module == "":
+		key.logger.SetLevel(level)
+	case exists:
+		key.logger.SetModuleLevel(key.module, level)
+	default:
+		key.logger.RemoveModuleLevel(key.module)
+	}
+}
+
+// setLevel changes the level of a logger or a module, with a ttl the level
+// which was set before the first temporary change is restored when it
+// expires
+func (h *LevelHandler) setLevel(key levelKey, level LoggingLevel, remove bool, ttl time.Duration) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	revert, reverting := h.reverts[key]
+	if reverting {
+		revert.timer.Stop()
+		delete(h.reverts, key)
+	}
+
+	if ttl > 0 {
+		previous, existed := h.current(key)
+		if reverting {
+			previous, existed = revert.previous, revert.existed
+		}
+
+		revert = &levelRevert{previous: previous, existed: existed}
+		revert.timer = time.AfterFunc(ttl, func() {
+			h.mux.Lock()
+			defer h.mux.Unlock()
+
+			if h.reverts[key] != revert {
+				return
+			}
+
+			delete(h.reverts, key)
+			h.apply(key, previous, existed)
+		})
+		h.reverts[key] = revert
+	}
+
+	h.apply(key, level, !remove)
+}
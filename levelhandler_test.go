@@ -0,0 +1,137 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func doLevelRequest(t *testing.T, h http.Handler, method, body string) (int, map[string]interface{}) {
+	r := httptest.NewRequest(method, "/log/level", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &m); err != nil {
+		t.Fatalf("invalid json %q: %v", w.Body.String(), err)
+	}
+
+	return w.Code, m
+}
+
+func TestLevelHandlerGet(t *testing.T) {
+	logging := NewLogging("test", DEBUG_LEVEL, 4)
+	logging.Module("db")
+	Register("handler-get", logging)
+	defer Unregister("handler-get")
+
+	code, m := doLevelRequest(t, NewLevelHandler(), http.MethodGet, "")
+	if code != http.StatusOK {
+		t.Errorf("unexpected status %d", code)
+		return
+	}
+
+	loggers, _ := m["loggers"].(map[string]interface{})
+	state, _ := loggers["handler-get"].(map[string]interface{})
+	if state["level"] != "Debug" {
+		t.Errorf("unexpected logger state %v", m)
+	}
+
+	modules, _ := state["modules"].(map[string]interface{})
+	if modules["db"] != "Debug" {
+		t.Errorf("unexpected module state %v", state)
+	}
+
+	if global, _ := m["global"].(map[string]interface{}); global["level"] == nil {
+		t.Errorf("global logger is missing %v", m)
+	}
+}
+
+func TestLevelHandlerSet(t *testing.T) {
+	logging := NewLogging("test", INFO_LEVEL, 4)
+	Register("handler-set", logging)
+	defer Unregister("handler-set")
+
+	h := NewLevelHandler()
+
+	code, m := doLevelRequest(t, h, http.MethodPut, `{"logger": "handler-set", "level": "warn"}`)
+	if code != http.StatusOK || m["level"] != "Warn" || logging.GetLevel() != WARN_LEVEL {
+		t.Errorf("unexpected response %d %v", code, m)
+	}
+
+	code, _ = doLevelRequest(t, h, http.MethodPost, `{"logger": "missing", "level": "warn"}`)
+	if code != http.StatusNotFound {
+		t.Errorf("unexpected status %d for unknown logger", code)
+	}
+
+	code, _ = doLevelRequest(t, h, http.MethodPut, `{"logger": "handler-set", "level": "verbose"}`)
+	if code != http.StatusBadRequest {
+		t.Errorf("unexpected status %d for invalid level", code)
+	}
+
+	code, _ = doLevelRequest(t, h, http.MethodDelete, "")
+	if code != http.StatusMethodNotAllowed {
+		t.Errorf("unexpected status %d for delete", code)
+	}
+}
+
+func TestLevelHandlerTTL(t *testing.T) {
+	logging := NewLogging("test", INFO_LEVEL, 4)
+	Register("handler-ttl", logging)
+	defer Unregister("handler-ttl")
+
+	h := NewLevelHandler()
+
+	doLevelRequest(t, h, http.MethodPut, `{"logger": "handler-ttl", "level": "debug", "ttl": "50ms"}`)
+	doLevelRequest(t, h, http.MethodPut, `{"logger": "handler-ttl", "level": "trace", "ttl": "50ms"}`)
+	if logging.GetLevel() != TRACE_LEVEL {
+		t.Errorf("unexpected level %v", logging.GetLevel())
+		return
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if logging.GetLevel() != INFO_LEVEL {
+		t.Errorf("level is not reverted, got %v", logging.GetLevel())
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for level := TRACE_LEVEL; level <= FATAL_LEVEL; level++ {
+		parsed, err := ParseLevel(strings.ToUpper(level.String()))
+		if err != nil || parsed != level {
+			t.Errorf("parse level %v failed", level)
+		}
+	}
+
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("expected error for unknown level")
+	}
+}
+
+func TestLevelHandlerModule(t *testing.T) {
+	logging := NewLogging("test", INFO_LEVEL, 4)
+	Register("handler-module", logging)
+	defer Unregister("handler-module")
+
+	h := NewLevelHandler()
+
+	code, m := doLevelRequest(t, h, http.MethodPut, `{"logger": "handler-module", "module": "db.*", "level": "debug", "ttl": "50ms"}`)
+	modules, _ := m["modules"].(map[string]interface{})
+	if code != http.StatusOK || modules["db.*"] != "Debug" || logging.levelOf("db.query") != DEBUG_LEVEL {
+		t.Errorf("unexpected response %d %v", code, m)
+		return
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if _, ok := logging.ModuleLevels()["db.*"]; ok {
+		t.Error("module level is not removed after ttl")
+	}
+
+	code, _ = doLevelRequest(t, h, http.MethodPut, `{"logger": "handler-module", "module": "db*", "level": "debug"}`)
+	if code != http.StatusBadRequest {
+		t.Errorf("unexpected status %d for invalid module pattern", code)
+	}
+}
@@ -0,0 +1,168 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+)
+
+// LogfmtFormatter prints every record as a line of logfmt key=value pairs
+func LogfmtFormatter(logRecord *LogRecord) *bytes.Buffer {
+	caller, line := logRecord.caller()
+
+	buf := pool.Get()
+	buf.Reset()
+
+	buf.WriteString("time=")
+	writeLogfmtString(buf, formatCacheTime(logRecord.timestamp()))
+
+	buf.WriteString(" level=")
+	buf.WriteString(logRecord.logLevel.String())
+
+	if len(logRecord.module) != 0 {
+		buf.WriteString(" module=")
+		writeLogfmtString(buf, logRecord.module)
+	}
+
+	if logRecord.enableCaller {
+		buf.WriteString(" caller=")
+		if needsLogfmtQuote(caller) {
+			writeLogfmtString(buf, caller+":"+strconv.Itoa(line))
+		} else {
+			buf.WriteString(caller)
+			buf.WriteString(":")
+			buf.WriteString(strconv.Itoa(line))
+		}
+
+		if len(logRecord.function) != 0 {
+			buf.WriteString(" func=")
+			writeLogfmtString(buf, logRecord.function)
+		}
+	}
+
+	buf.WriteString(" msg=")
+	writeLogfmtString(buf, logRecord.message())
+
+	if logRecord.err != nil {
+		buf.WriteString(" error=")
+		writeLogfmtString(buf, logRecord.err.Error())
+	}
+
+	writeFields(buf, logRecord.fields)
+
+	if len(logRecord.stack) != 0 {
+		// the stack is a single value, one "func file:line" per line
+		stack := &bytes.Buffer{}
+		for i, frame := range logRecord.stack {
+			if i > 0 {
+				stack.WriteString("\n")
+			}
+			stack.WriteString(frame.Function)
+			stack.WriteString(" ")
+			stack.WriteString(frame.File)
+			stack.WriteString(":")
+			stack.WriteString(strconv.Itoa(frame.Line))
+		}
+
+		buf.WriteString(" stack=")
+		writeLogfmtString(buf, stack.String())
+	}
+	buf.WriteString("\n")
+
+	return buf
+}
+
+// writeLogfmtKey writes key with the characters which are not allowed in
+// a logfmt key replaced by '_'
+func writeLogfmtKey(buf *bytes.Buffer, key string) {
+	if key == "" {
+		buf.WriteString("_")
+		return
+	}
+
+	for _, r := range key {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError {
+			buf.WriteByte('_')
+			continue
+		}
+
+		buf.WriteRune(r)
+	}
+}
+
+func writeLogfmtValue(buf *bytes.Buffer, value interface{}) {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteString("nil")
+	case string:
+		writeLogfmtString(buf, v)
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case int:
+		buf.WriteString(strconv.Itoa(v))
+	case int64:
+		buf.WriteString(strconv.FormatInt(v, 10))
+	case uint64:
+		buf.WriteString(strconv.FormatUint(v, 10))
+	case float64:
+		buf.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+	default:
+		writeLogfmtString(buf, fmt.Sprint(v))
+	}
+}
+
+// writeLogfmtString quotes s when it is empty or contains spaces, '=',
+// quotes or control characters
+func writeLogfmtString(buf *bytes.Buffer, s string) {
+	if !needsLogfmtQuote(s) {
+		buf.WriteString(s)
+		return
+	}
+
+	buf.WriteByte('"')
+
+	start := 0
+	for i := 0; i < len(s); i++ {
+		b := s[i]
+		if b >= 0x20 && b != '"' && b != '\\' && b != 0x7f {
+			continue
+		}
+
+		buf.WriteString(s[start:i])
+		switch b {
+		case '"', '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(b)
+		case '\n':
+			buf.WriteString("\\n")
+		case '\r':
+			buf.WriteString("\\r")
+		case '\t':
+			buf.WriteString("\\t")
+		default:
+			buf.WriteString("\\x")
+			buf.WriteByte(hex[b>>4])
+			buf.WriteByte(hex[b&0xf])
+		}
+		start = i + 1
+	}
+
+	buf.WriteString(s[start:])
+	buf.WriteByte('"')
+}
+
+func needsLogfmtQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+
+	for i := 0; i < len(s); i++ {
+		b := s[i]
+		if b <= ' ' || b == '=' || b == '"' || b == '\\' || b == 0x7f {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,74 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestLogfmtFormatter(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logging := NewLoggingWithFormater(INFO_LEVEL, 2, LogfmtFormatter)
+	logging.SetOutPut(buf)
+
+	logging.Module("db").With("query", "select 1", "rows", 3).WithError(errors.New("a=b")).Warn("line1\nline2")
+
+	s := buf.String()
+	for _, expected := range []string{
+		" level=Warn module=db caller=",
+		` msg="line1\nline2" error="a=b" query="select 1" rows=3` + "\n",
+	} {
+		if !strings.Contains(s, expected) {
+			t.Errorf("expected %q in %q", expected, s)
+		}
+	}
+
+	if !strings.HasPrefix(s, `time="`) {
+		t.Errorf("unexpected time in %q", s)
+	}
+}
+
+func TestWriteLogfmtString(t *testing.T) {
+	cases := map[string]string{
+		"plain":       "plain",
+		"":            `""`,
+		"with space":  `"with space"`,
+		"k=v":         `"k=v"`,
+		`say "hi"`:    `"say \"hi\""`,
+		"tab\tnew\n":  `"tab\tnew\n"`,
+		"ctrl\x01":    `"ctrl\x01"`,
+		"back\\slash": `"back\\slash"`,
+	}
+
+	for s, expected := range cases {
+		buf := &bytes.Buffer{}
+		writeLogfmtString(buf, s)
+
+		if buf.String() != expected {
+			t.Errorf("expected %s, got %s", expected, buf.String())
+		}
+	}
+}
+
+func BenchmarkDefaultFormatter(b *testing.B) {
+	logging := NewLogging("test", INFO_LEVEL, 4)
+	logging.SetOutPut(ioutil.Discard)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logging.Info("Test Message")
+	}
+}
+
+func BenchmarkLogfmtFormatter(b *testing.B) {
+	logging := NewLoggingWithFormater(INFO_LEVEL, 4, LogfmtFormatter)
+	logging.SetOutPut(ioutil.Discard)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logging.Info("Test Message")
+	}
+}
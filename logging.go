@@ -2,9 +2,12 @@ package log
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"os"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -13,8 +16,29 @@ type LogRotateConfig struct {
 	Prefix        string `json:"prefix"`
 	FileDir       string `json:"fileDir"`
 	MaxSize       int64  `json:"maxSize"`
-	// unit is second
+	// the rotated files older than MaxLogLife are removed, unit is second,
+	// 0 means no limit
 	MaxLogLife int64 `json:"maxLogLife"`
+	// max number of rotated files, 0 means no limit
+	MaxBackups int `json:"maxBackups"`
+	// max size of all the log files in FileDir, unit is MB, 0 means no limit
+	MaxTotalSize int64 `json:"maxTotalSize"`
+	// the newest MinKeep rotated files are never removed
+	MinKeep int `json:"minKeep"`
+	// the rotated files are moved to ArchiveDir instead of being removed
+	ArchiveDir string `json:"archiveDir"`
+	// only report the files which would be removed
+	DryRun bool `json:"dryRun"`
+	// name of the codec compressing the rotated files, e.g. "gzip", empty
+	// means the files are not compressed
+	Compress string `json:"compress"`
+	// rotates the file on wall-clock boundaries, "hourly", "daily" or a
+	// duration such as "30m", it is combined with MaxSize and whichever
+	// fires first wins. Empty means no time based rotation
+	RotateInterval string `json:"rotateInterval"`
+	// use UTC instead of local time for the rotate boundaries and the
+	// file names
+	UTC bool `json:"utc"`
 }
 
 type LoggingLevel int
@@ -47,6 +71,19 @@ func (level LoggingLevel) String() string {
 	}
 }
 
+// ParseLevel parses the name of a level case-insensitively, e.g. "info"
+func ParseLevel(s string) (LoggingLevel, error) {
+	for level := TRACE_LEVEL; level <= FATAL_LEVEL; level++ {
+		if strings.EqualFold(s, level.String()) {
+			return level, nil
+		}
+	}
+
+	return INFO_LEVEL, fmt.Errorf("unknown logging level %q", s)
+}
+
+// NewLogging creates a logging, callerLevel is only kept for compatibility,
+// the caller is found by walking the stack, see RegisterHelperPackage
 func NewLogging(name string, level LoggingLevel, callerLevel int) *logging {
 	if level < TRACE_LEVEL || level > FATAL_LEVEL {
 		level = INFO_LEVEL
@@ -61,6 +98,7 @@ func NewLogging(name string, level LoggingLevel, callerLevel int) *logging {
 		Formater:     DefaultFormater,
 		exitChan:     make(chan struct{}, 2),
 	}
+	logging.storeLevels()
 
 	return logging
 }
@@ -79,42 +117,158 @@ func NewLoggingWithFormater(level LoggingLevel, callerLevel int, formater Format
 		Formater:     formater,
 		exitChan:     make(chan struct{}, 2),
 	}
+	logging.storeLevels()
 
 	return logging
 }
 
 type logging struct {
-	mux  sync.Mutex
-	name string
+	mux sync.Mutex
+	// writeMux serializes the writes to output, so a slow output does not
+	// block the callers holding mux. It is always locked before mux
+	writeMux sync.Mutex
+	name     string
 	// default log level
 	level        LoggingLevel
 	output       io.Writer
 	pool         *BufferPool
 	enableCaller bool
-	// default caller level
+	// the caller depth of the records, only used by Caller and CallerLevel
 	callerLevel int
 	Formater    func(logRecord *LogRecord) *bytes.Buffer
 
 	LogRotateConfig
 	exitChan chan struct{}
-
-	isStarted bool
+	// the output replaced by the log file, it is restored when the file
+	// logging is switched off
+	plainOutput io.Writer
+	// the log file opened by Start or UpdateConfig, the only default output
+	// closed by the logging
+	fileOutput *FileOutput
+
+	sinks        []*sink
+	errorHandler func(err error)
+	async        *asyncWriter
+	// the names passed to Module
+	modules      map[string]struct{}
+	moduleLevels []moduleLevel
+	// levels is a *levelTable copy of level and moduleLevels, read by
+	// levelOf without locking mux
+	levels  atomic.Value
+	sampler *Sampler
+	// how the caller is printed
+	callerOptions CallerOptions
+	// the records at or above stackLevel carry a stack trace
+	stackEnabled bool
+	stackLevel   LoggingLevel
+	// fired for the records, see AddHook
+	hooks []*hook
+	dedup *deduper
+
+	// Start was called, the rotate goroutine is running once isStarted is
+	// set
+	startCalled bool
+	isStarted   bool
 }
 
 func (l *logging) Close() {
+	l.flushDedup()
+
 	l.mux.Lock()
-	defer l.mux.Unlock()
+	async := l.async
+	l.mux.Unlock()
+
+	if async != nil {
+		async.close()
+	}
 
-	if l.exitChan != nil {
+	for _, s := range l.setSinks(nil) {
+		s.retire()
+	}
+
+	l.writeMux.Lock()
+	defer l.writeMux.Unlock()
+
+	l.mux.Lock()
+	if l.isStarted && l.exitChan != nil {
 		l.exitChan <- struct{}{}
+		l.isStarted = false
+	}
+	fileOutput := l.fileOutput
+	l.fileOutput = nil
+	l.mux.Unlock()
+
+	// the outputs given by the caller, e.g. os.Stdout, are left open
+	if fileOutput != nil {
+		fileOutput.Close()
 	}
 }
 
+// UpdateConfig applies cfg, once Start is called the changes reach the
+// running file output: a new file is opened when FileDir or Prefix change
+// and EnableLogFile switches the file logging on or off. Nothing is changed
+// if it fails, the error is passed to the error handler
 func (l *logging) UpdateConfig(cfg LogRotateConfig) {
+	if err := l.updateConfig(cfg); err != nil {
+		l.handleError(fmt.Errorf("update config: %v", err))
+	}
+}
+
+func (l *logging) updateConfig(cfg LogRotateConfig) error {
+	if _, err := parseRotateInterval(cfg.RotateInterval); err != nil {
+		return err
+	}
+
+	l.writeMux.Lock()
+	defer l.writeMux.Unlock()
+
 	l.mux.Lock()
-	defer l.mux.Unlock()
+	startCalled := l.startCalled
+	fileOutput := l.fileOutput
+	l.mux.Unlock()
+
+	// only the file opened by the logging is replaced, an output set by
+	// SetOutPut is kept
+	isFile := fileOutput != nil
+
+	switch {
+	case !startCalled:
+	case cfg.EnableLogFile && isFile:
+		if err := fileOutput.Reconfigure(cfg); err != nil {
+			return err
+		}
+	case cfg.EnableLogFile:
+		newOutput, err := NewFileOutput(cfg)
+		if err != nil {
+			return err
+		}
 
+		l.mux.Lock()
+		l.plainOutput = l.output
+		l.output = newOutput
+		l.fileOutput = newOutput.(*FileOutput)
+		l.mux.Unlock()
+	case isFile:
+		l.mux.Lock()
+		l.output = l.plainOutput
+		if l.output == nil {
+			l.output = os.Stdout
+		}
+		l.fileOutput = nil
+		l.mux.Unlock()
+
+		fileOutput.Close()
+	}
+
+	l.mux.Lock()
 	l.LogRotateConfig = cfg
+	l.mux.Unlock()
+
+	if startCalled && cfg.EnableLogFile {
+		l.startRotate()
+	}
+
+	return nil
 }
 
 func (l *logging) SetOutPut(w io.Writer) {
@@ -123,15 +277,34 @@ func (l *logging) SetOutPut(w io.Writer) {
 	l.output = w
 }
 
-func (l *logging) Write(buf *bytes.Buffer) {
+func (l *logging) SetFormatter(formatter Formatter) {
 	l.mux.Lock()
 	defer l.mux.Unlock()
+	l.Formater = formatter
+}
+
+func (l *logging) Write(buf *bytes.Buffer) {
+	l.write(INFO_LEVEL, buf)
+	l.pool.Put(buf)
+}
+
+func (l *logging) write(level LoggingLevel, buf *bytes.Buffer) {
+	// output is read under writeMux, so it is never written once
+	// UpdateConfig has replaced it
+	l.writeMux.Lock()
+	l.mux.Lock()
+	output := l.output
+	l.mux.Unlock()
 
-	if _, err := l.output.Write(buf.Bytes()); err != nil {
-		panic(err)
+	var err error
+	if output != nil {
+		_, err = writeLevel(output, level, buf.Bytes())
 	}
+	l.writeMux.Unlock()
 
-	l.pool.Put(buf)
+	if err != nil {
+		l.handleError(err)
+	}
 }
 
 func (l *logging) SetLevel(level LoggingLevel) {
@@ -139,10 +312,18 @@ func (l *logging) SetLevel(level LoggingLevel) {
 	defer l.mux.Unlock()
 
 	l.level = level
+	l.storeLevels()
+}
+
+func (l *logging) GetLevel() LoggingLevel {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	return l.level
 }
 
 func (l *logging) print(level LoggingLevel, args ...interface{}) {
-	if l.level <= level {
+	if l.levelOf("") <= level {
 		record := &LogRecord{
 			logLevel:     level,
 			callerLevel:  l.callerLevel,
@@ -155,7 +336,7 @@ func (l *logging) print(level LoggingLevel, args ...interface{}) {
 }
 
 func (l *logging) printf(level LoggingLevel, format string, args ...interface{}) {
-	if l.level <= level {
+	if l.levelOf("") <= level {
 		record := &LogRecord{
 			logLevel:     level,
 			callerLevel:  l.callerLevel,
@@ -189,6 +370,7 @@ func (l *logging) Error(args ...interface{}) {
 
 func (l *logging) Fatal(args ...interface{}) {
 	l.print(ERROR_LEVEL, args...)
+	l.Flush()
 	os.Exit(1)
 }
 
@@ -214,13 +396,15 @@ func (l *logging) Errorf(format string, args ...interface{}) {
 
 func (l *logging) Fatalf(format string, args ...interface{}) {
 	l.printf(FATAL_LEVEL, format, args...)
+	l.Flush()
 	os.Exit(1)
 }
 
 func (l *logging) Module(moudle string) *LogRecord {
+	l.addModule(moudle)
+
 	return &LogRecord{
 		module:       moudle,
-		logLevel:     l.level,
 		callerLevel:  l.callerLevel,
 		enableCaller: l.enableCaller,
 		logger:       l,
@@ -229,39 +413,130 @@ func (l *logging) Module(moudle string) *LogRecord {
 
 func (l *logging) Caller(level int) *LogRecord {
 	return &LogRecord{
-		callerLevel:  level,
-		enableCaller: true,
-		logger:       l,
+		callerLevel:    level,
+		explicitCaller: true,
+		enableCaller:   true,
+		logger:         l,
 	}
 }
 
 func (l *logging) LogLevel(logLevel LoggingLevel) *LogRecord {
 	return &LogRecord{
 		logLevel:     logLevel,
+		fixedLevel:   true,
 		callerLevel:  l.callerLevel,
 		enableCaller: l.enableCaller,
 		logger:       l,
 	}
 }
 
-func (l *logging) Start() {
-	if !l.EnableLogFile {
-		return
+func (l *logging) With(keyValues ...interface{}) *LogRecord {
+	record := &LogRecord{
+		callerLevel:  l.callerLevel,
+		enableCaller: l.enableCaller,
+		logger:       l,
+		fields:       toFields(keyValues),
 	}
 
-	l.mux.Lock()
-	if l.isStarted {
-		l.mux.Unlock()
-		return
+	return record
+}
+
+func (l *logging) WithFields(fields Fields) *LogRecord {
+	record := &LogRecord{
+		callerLevel:  l.callerLevel,
+		enableCaller: l.enableCaller,
+		logger:       l,
+		fields:       fields.toSlice(),
 	}
+
+	return record
+}
+
+func (l *logging) WithError(err error) *LogRecord {
+	record := &LogRecord{
+		callerLevel:  l.callerLevel,
+		enableCaller: l.enableCaller,
+		logger:       l,
+		err:          err,
+	}
+
+	return record
+}
+
+// rotate rotates the default output and the sinks
+func (l *logging) rotate() {
+	l.writeMux.Lock()
+	l.mux.Lock()
+	output := l.output
+	sinks := l.sinks
 	l.mux.Unlock()
 
-	output, err := NewFileOutput(l.LogRotateConfig)
+	var err error
+	if output, ok := output.(OutPut); ok {
+		err = output.Rotate()
+	}
+	l.writeMux.Unlock()
+
 	if err != nil {
-		panic(err)
+		l.handleError(err)
 	}
 
-	l.SetOutPut(output)
+	for _, s := range sinks {
+		s.mux.Lock()
+		err := s.Output.Rotate()
+		s.mux.Unlock()
+
+		if err != nil {
+			l.handleError(err)
+		}
+	}
+}
+
+// Start opens the log file when EnableLogFile is set and rotates the outputs
+// every second, a log file which can not be opened is passed to the error
+// handler
+func (l *logging) Start() {
+	l.mux.Lock()
+	l.startCalled = true
+	hasSinks := len(l.sinks) != 0
+	enableLogFile := l.EnableLogFile
+	isStarted := l.isStarted
+	l.mux.Unlock()
+
+	if (!enableLogFile && !hasSinks) || isStarted {
+		return
+	}
+
+	if enableLogFile {
+		l.writeMux.Lock()
+		output, err := NewFileOutput(l.LogRotateConfig)
+		if err != nil {
+			l.writeMux.Unlock()
+			l.handleError(fmt.Errorf("start: %v", err))
+			return
+		}
+
+		l.mux.Lock()
+		l.plainOutput = l.output
+		l.output = output
+		l.fileOutput = output.(*FileOutput)
+		l.mux.Unlock()
+		l.writeMux.Unlock()
+	}
+
+	l.startRotate()
+}
+
+// startRotate starts the goroutine rotating the outputs unless it is
+// running
+func (l *logging) startRotate() {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if l.isStarted {
+		return
+	}
+	l.isStarted = true
 
 	go func() {
 		ticker := time.NewTicker(time.Second * 1)
@@ -270,23 +545,10 @@ func (l *logging) Start() {
 		for {
 			select {
 			case <-ticker.C:
-				l.mux.Lock()
-				fileOutput := l.output.(OutPut)
-				if err := fileOutput.Rotate(); err != nil {
-					panic(err)
-				}
-
-				l.mux.Unlock()
+				l.rotate()
 			case <-l.exitChan:
-				if closer, ok := l.output.(io.WriteCloser); ok {
-					closer.Close()
-				}
 				return
 			}
 		}
 	}()
-
-	l.mux.Lock()
-	l.isStarted = true
-	l.mux.Unlock()
 }
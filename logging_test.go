@@ -2,6 +2,9 @@ package log
 
 import (
 	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -101,3 +104,175 @@ func TestPrintLevel(t *testing.T) {
 		return
 	}
 }
+
+func TestUpdateConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	buf := &bytes.Buffer{}
+	cfg := LogRotateConfig{
+		EnableLogFile: true,
+		Prefix:        "first",
+		FileDir:       filepath.Join(dir, "first"),
+		MaxSize:       10,
+	}
+
+	var errs []error
+	l := NewLogging("test", INFO_LEVEL, 4)
+	l.SetOutPut(buf)
+	l.SetErrorHandler(func(err error) {
+		errs = append(errs, err)
+	})
+	l.UpdateConfig(cfg)
+	l.Start()
+	defer l.Close()
+
+	l.Info("first record")
+
+	cfg.Prefix = "second"
+	cfg.FileDir = filepath.Join(dir, "second")
+	l.UpdateConfig(cfg)
+	l.Info("second record")
+
+	cfg.EnableLogFile = false
+	l.UpdateConfig(cfg)
+	l.Info("plain record")
+
+	if len(errs) != 0 {
+		t.Errorf("unexpected errors %v", errs)
+		return
+	}
+
+	cfg.RotateInterval = "weekly"
+	l.UpdateConfig(cfg)
+	if len(errs) != 1 || !strings.HasPrefix(errs[0].Error(), "update config: ") {
+		t.Errorf("expected an error for an invalid rotate interval, got %v", errs)
+		return
+	}
+
+	first, err := readLogDir(filepath.Join(dir, "first"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	second, err := readLogDir(filepath.Join(dir, "second"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if !strings.Contains(first, "first record") || strings.Contains(first, "second record") {
+		t.Errorf("unexpected first log file %s", first)
+	}
+
+	if !strings.Contains(second, "second record") || strings.Contains(second, "plain record") {
+		t.Errorf("unexpected second log file %s", second)
+	}
+
+	if !strings.Contains(buf.String(), "plain record") || strings.Contains(buf.String(), "first record") {
+		t.Errorf("unexpected plain output %s", buf.String())
+	}
+}
+
+// closeRecorder records whether it is closed
+type closeRecorder struct {
+	BufferOutput
+	closed bool
+}
+
+func (o *closeRecorder) Close() error {
+	o.closed = true
+	return nil
+}
+
+func TestCloseOutputs(t *testing.T) {
+	output := &closeRecorder{}
+	sinkOutput := &closeRecorder{}
+
+	l := NewLogging("test", INFO_LEVEL, 4)
+	l.SetOutPut(output)
+	l.AddSink(Sink{Output: sinkOutput})
+	l.Start()
+	l.Info("hello")
+	l.Close()
+
+	// the output is given by the caller, e.g. os.Stdout
+	if output.closed {
+		t.Error("the default output is closed")
+	}
+
+	if !sinkOutput.closed {
+		t.Error("the sink output is not closed")
+	}
+
+	if !strings.Contains(output.String(), "hello") || !strings.Contains(sinkOutput.String(), "hello") {
+		t.Errorf("unexpected records %q %q", output.String(), sinkOutput.String())
+	}
+}
+
+func TestCloseAfterLogFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	output := &closeRecorder{}
+	cfg := LogRotateConfig{EnableLogFile: true, Prefix: "test", FileDir: dir, MaxSize: 10}
+
+	l := NewLogging("test", INFO_LEVEL, 4)
+	l.SetOutPut(output)
+	l.UpdateConfig(cfg)
+	l.Start()
+	l.Info("file record")
+
+	// the output set by SetOutPut is restored, not closed
+	cfg.EnableLogFile = false
+	l.UpdateConfig(cfg)
+	l.Info("plain record")
+	l.Close()
+
+	if output.closed {
+		t.Error("the restored output is closed")
+	}
+
+	if !strings.Contains(output.String(), "plain record") || strings.Contains(output.String(), "file record") {
+		t.Errorf("unexpected output %q", output.String())
+	}
+}
+
+func TestStartError(t *testing.T) {
+	file, err := ioutil.TempFile("", "log")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	file.Close()
+	defer os.Remove(file.Name())
+
+	var errs []error
+	l := NewLogging("test", INFO_LEVEL, 4)
+	l.SetOutPut(&bytes.Buffer{})
+	l.SetErrorHandler(func(err error) {
+		errs = append(errs, err)
+	})
+
+	// a file can not be the directory of the logs
+	l.UpdateConfig(LogRotateConfig{EnableLogFile: true, Prefix: "test", FileDir: file.Name(), MaxSize: 10})
+	l.Start()
+
+	if len(errs) != 1 || !strings.HasPrefix(errs[0].Error(), "start: ") {
+		t.Errorf("expected an error from Start, got %v", errs)
+		return
+	}
+
+	// the logger is still usable
+	l.Info("hello")
+	l.Close()
+}
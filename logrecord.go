@@ -1,6 +1,11 @@
 package log
 
-import "os"
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+)
 
 type LogRecord struct {
 	format       string
@@ -10,119 +15,218 @@ type LogRecord struct {
 	enableCaller bool
 	logLevel     LoggingLevel
 	logger       *logging
+	fields       []Field
+	err          error
+	// logLevel is used as the threshold instead of the level of the
+	// logger and the module, see logging.LogLevel
+	fixedLevel bool
+	// the sampler of the record, the one of the logger is used when it is
+	// nil
+	sampler *Sampler
+
+	// the caller is resolved once before the record is formatted
+	callerResolved bool
+	// callerLevel is the depth of the caller instead of walking the stack,
+	// see logging.Caller
+	explicitCaller bool
+	file           string
+	line           int
+	// the package qualified name of the function, only set when it is
+	// printed
+	function string
+
+	// the time of the record, the formatters use the current time when it
+	// is not set
+	time time.Time
+
+	// capture a stack trace whatever the level, see WithStack
+	withStack bool
+	stack     []runtime.Frame
+}
+
+// enabled reports whether a message at level passes the threshold of the
+// record, it is looked up on every call so level changes reach existing
+// records
+func (l *LogRecord) enabled(level LoggingLevel) bool {
+	if l.fixedLevel {
+		return l.logLevel <= level
+	}
+
+	return l.logger.levelOf(l.module) <= level
+}
+
+// caller returns the file and the line the record is logged at
+func (l *LogRecord) caller() (string, int) {
+	if l.callerResolved {
+		return l.file, l.line
+	}
+
+	if !l.enableCaller {
+		return "", 0
+	}
+
+	if l.explicitCaller {
+		_, file, line, _ := runtime.Caller(l.callerLevel + 1)
+		return file, line
+	}
+
+	frame, _ := callerFrame()
+	return frame.File, frame.Line
+}
+
+// timestamp returns the time the record is printed with
+func (l *LogRecord) timestamp() time.Time {
+	if l.time.IsZero() {
+		return time.Now()
+	}
+
+	return l.time
+}
+
+// message returns the formatted message of the record
+func (l *LogRecord) message() string {
+	if len(l.format) == 0 {
+		return fmt.Sprint(l.args...)
+	}
+
+	return fmt.Sprintf(l.format, l.args...)
+}
+
+// entry returns a copy of the record which is used to print a single
+// message, so the record itself can be shared and reused
+func (l *LogRecord) entry(level LoggingLevel, format string, args []interface{}) *LogRecord {
+	entry := *l
+	entry.logLevel = level
+	entry.format = format
+	entry.args = args
+
+	return &entry
 }
 
 func (l *LogRecord) print(args ...interface{}) {
 	l.args = args
-	l.logger.Write(l.logger.Formater(l))
+	l.logger.dispatch(l)
 }
 
 func (l *LogRecord) printf(format string, args ...interface{}) {
 	l.args = args
 	l.format = format
-	l.logger.Write(l.logger.Formater(l))
+	l.logger.dispatch(l)
 }
 
 func (l *LogRecord) Trace(args ...interface{}) {
-	if l.logLevel <= TRACE_LEVEL {
-		l.args = args
-		l.logger.Write(l.logger.Formater(l))
+	if l.enabled(TRACE_LEVEL) {
+		l.logger.dispatch(l.entry(TRACE_LEVEL, "", args))
 	}
 }
 
 func (l *LogRecord) Debug(args ...interface{}) {
-	if l.logLevel <= DEBUG_LEVEL {
-		l.args = args
-		l.logger.Write(l.logger.Formater(l))
+	if l.enabled(DEBUG_LEVEL) {
+		l.logger.dispatch(l.entry(DEBUG_LEVEL, "", args))
 	}
 }
 
 func (l *LogRecord) Info(args ...interface{}) {
-	if l.logLevel <= INFO_LEVEL {
-		l.args = args
-		l.logger.Write(l.logger.Formater(l))
+	if l.enabled(INFO_LEVEL) {
+		l.logger.dispatch(l.entry(INFO_LEVEL, "", args))
 	}
 }
 
 func (l *LogRecord) Warn(args ...interface{}) {
-	if l.logLevel <= WARN_LEVEL {
-		l.args = args
-		l.logger.Write(l.logger.Formater(l))
+	if l.enabled(WARN_LEVEL) {
+		l.logger.dispatch(l.entry(WARN_LEVEL, "", args))
 	}
 }
 
 func (l *LogRecord) Error(args ...interface{}) {
-	if l.logLevel <= ERROR_LEVEL {
-		l.args = args
-		l.logger.Write(l.logger.Formater(l))
+	if l.enabled(ERROR_LEVEL) {
+		l.logger.dispatch(l.entry(ERROR_LEVEL, "", args))
 	}
 }
 
 func (l *LogRecord) Fatal(args ...interface{}) {
-	if l.logLevel <= FATAL_LEVEL {
-		l.args = args
-		l.logger.Write(l.logger.Formater(l))
+	if l.enabled(FATAL_LEVEL) {
+		l.logger.dispatch(l.entry(FATAL_LEVEL, "", args))
+		l.logger.Flush()
 		os.Exit(0)
 	}
 }
 
 func (l *LogRecord) Tracef(format string, args ...interface{}) {
-	if l.logLevel <= TRACE_LEVEL {
-		l.args = args
-		l.format = format
-		l.logger.Write(l.logger.Formater(l))
+	if l.enabled(TRACE_LEVEL) {
+		l.logger.dispatch(l.entry(TRACE_LEVEL, format, args))
 	}
 }
 
 func (l *LogRecord) Debugf(format string, args ...interface{}) {
-	if l.logLevel <= DEBUG_LEVEL {
-		l.args = args
-		l.format = format
-		l.logger.Write(l.logger.Formater(l))
+	if l.enabled(DEBUG_LEVEL) {
+		l.logger.dispatch(l.entry(DEBUG_LEVEL, format, args))
 	}
 }
 
 func (l *LogRecord) Infof(format string, args ...interface{}) {
-	if l.logLevel <= INFO_LEVEL {
-		l.args = args
-		l.format = format
-		l.logger.Write(l.logger.Formater(l))
+	if l.enabled(INFO_LEVEL) {
+		l.logger.dispatch(l.entry(INFO_LEVEL, format, args))
 	}
 }
 
 func (l *LogRecord) Warnf(format string, args ...interface{}) {
-	if l.logLevel <= WARN_LEVEL {
-		l.args = args
-		l.format = format
-		l.logger.Write(l.logger.Formater(l))
+	if l.enabled(WARN_LEVEL) {
+		l.logger.dispatch(l.entry(WARN_LEVEL, format, args))
 	}
 }
 
 func (l *LogRecord) Errorf(format string, args ...interface{}) {
-	if l.logLevel <= ERROR_LEVEL {
-		l.args = args
-		l.format = format
-		l.logger.Write(l.logger.Formater(l))
+	if l.enabled(ERROR_LEVEL) {
+		l.logger.dispatch(l.entry(ERROR_LEVEL, format, args))
 	}
 }
 
 func (l *LogRecord) Fatalf(format string, args ...interface{}) {
-	if l.logLevel <= FATAL_LEVEL {
-		l.args = args
-		l.format = format
-		l.logger.Write(l.logger.Formater(l))
+	if l.enabled(FATAL_LEVEL) {
+		l.logger.dispatch(l.entry(FATAL_LEVEL, format, args))
 	}
 
+	l.logger.Flush()
 	os.Exit(0)
 }
 
 func (l *LogRecord) Module(module string) *LogRecord {
+	l.logger.addModule(module)
 	l.module = module
 	return l
 }
 
 func (l *LogRecord) CallerLevel(callerLevel int) *LogRecord {
 	l.callerLevel = callerLevel
+	l.explicitCaller = true
 	l.enableCaller = true
 	return l
 }
+
+// With returns a child record carrying the fields of l extended by the given
+// alternating keys and values, l itself is not modified
+func (l *LogRecord) With(keyValues ...interface{}) *LogRecord {
+	child := *l
+	child.fields = mergeFields(l.fields, toFields(keyValues))
+	return &child
+}
+
+// WithFields is like With but takes the fields as a map
+func (l *LogRecord) WithFields(fields Fields) *LogRecord {
+	child := *l
+	child.fields = mergeFields(l.fields, fields.toSlice())
+	return &child
+}
+
+// WithError returns a child record carrying err
+func (l *LogRecord) WithError(err error) *LogRecord {
+	child := *l
+	child.err = err
+	return &child
+}
+
+// Fields returns a copy of the fields attached to the record
+func (l *LogRecord) Fields() []Field {
+	return mergeFields(l.fields, nil)
+}
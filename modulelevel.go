@@ -0,0 +1,172 @@
+package log
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// levelTable is the level of a logging and of its modules, it is never
+// modified once stored
+type levelTable struct {
+	level   LoggingLevel
+	modules []moduleLevel
+}
+
+// moduleLevel is the level of the modules matching pattern, a pattern is
+// either a module name, a prefix such as "db.*" matching "db" and every
+// "db.xxx" module, or "*" matching every module
+type moduleLevel struct {
+	pattern string
+	level   LoggingLevel
+}
+
+func validModulePattern(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("empty module pattern")
+	}
+
+	if i := strings.Index(pattern, "*"); i >= 0 && (i != len(pattern)-1 || (pattern != "*" && !strings.HasSuffix(pattern, ".*"))) {
+		return fmt.Errorf("invalid module pattern %q, a wildcard is only allowed as \"*\" or a \".*\" suffix", pattern)
+	}
+
+	return nil
+}
+
+// matchModule returns how specific the match of pattern against module is,
+// or -1 if it does not match
+func matchModule(pattern, module string) int {
+	if pattern == module {
+		// an exact match wins over every wildcard
+		return len(pattern) + 1
+	}
+
+	if pattern == "*" {
+		return 0
+	}
+
+	if strings.HasSuffix(pattern, ".*") {
+		prefix := strings.TrimSuffix(pattern, ".*")
+		if module == prefix || strings.HasPrefix(module, prefix+".") {
+			return len(prefix)
+		}
+	}
+
+	return -1
+}
+
+// SetModuleLevel sets the level of the modules matching pattern, e.g. "db"
+// or "db.*", the most specific pattern wins
+func (l *logging) SetModuleLevel(pattern string, level LoggingLevel) error {
+	if err := validModulePattern(pattern); err != nil {
+		return err
+	}
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	for i := range l.moduleLevels {
+		if l.moduleLevels[i].pattern == pattern {
+			l.moduleLevels[i].level = level
+			l.storeLevels()
+			return nil
+		}
+	}
+
+	l.moduleLevels = append(l.moduleLevels, moduleLevel{pattern: pattern, level: level})
+	l.storeLevels()
+	return nil
+}
+
+// RemoveModuleLevel makes the modules matching pattern use the level of the
+// logger again
+func (l *logging) RemoveModuleLevel(pattern string) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	for i := range l.moduleLevels {
+		if l.moduleLevels[i].pattern == pattern {
+			l.moduleLevels = append(l.moduleLevels[:i], l.moduleLevels[i+1:]...)
+			l.storeLevels()
+			return
+		}
+	}
+}
+
+// ModuleLevels returns the configured module patterns and their levels
+func (l *logging) ModuleLevels() map[string]LoggingLevel {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	levels := make(map[string]LoggingLevel, len(l.moduleLevels))
+	for _, moduleLevel := range l.moduleLevels {
+		levels[moduleLevel.pattern] = moduleLevel.level
+	}
+
+	return levels
+}
+
+// moduleLevel returns the level configured for pattern
+func (l *logging) moduleLevel(pattern string) (LoggingLevel, bool) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	for _, moduleLevel := range l.moduleLevels {
+		if moduleLevel.pattern == pattern {
+			return moduleLevel.level, true
+		}
+	}
+
+	return 0, false
+}
+
+// storeLevels publishes level and moduleLevels to levelOf, the callers hold
+// l.mux
+func (l *logging) storeLevels() {
+	modules := make([]moduleLevel, len(l.moduleLevels))
+	copy(modules, l.moduleLevels)
+
+	l.levels.Store(&levelTable{level: l.level, modules: modules})
+}
+
+// levelOf returns the effective level of module, the records without a
+// module use the level of the logger. It is called for every record, so it
+// does not lock l.mux
+func (l *logging) levelOf(module string) LoggingLevel {
+	table := l.levels.Load().(*levelTable)
+
+	level := table.level
+	if module == "" {
+		return level
+	}
+
+	best := -1
+	for _, moduleLevel := range table.modules {
+		if match := matchModule(moduleLevel.pattern, module); match > best {
+			best = match
+			level = moduleLevel.level
+		}
+	}
+
+	return level
+}
+
+// knownModules returns the modules used with l and the configured patterns
+func (l *logging) knownModules() []string {
+	names := map[string]struct{}{}
+	for _, module := range l.Modules() {
+		names[module] = struct{}{}
+	}
+
+	for pattern := range l.ModuleLevels() {
+		names[pattern] = struct{}{}
+	}
+
+	modules := make([]string, 0, len(names))
+	for name := range names {
+		modules = append(modules, name)
+	}
+	sort.Strings(modules)
+
+	return modules
+}
@@ -0,0 +1,115 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestModuleLevel(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logging := NewLogging("test", INFO_LEVEL, 4)
+	logging.SetOutPut(buf)
+
+	db := logging.Module("db")
+	query := logging.Module("db.query")
+	http := logging.Module("http")
+
+	if err := logging.SetModuleLevel("db.*", DEBUG_LEVEL); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := logging.SetModuleLevel("db.query", TRACE_LEVEL); err != nil {
+		t.Error(err)
+		return
+	}
+
+	cases := []struct {
+		record   *LogRecord
+		level    LoggingLevel
+		expected bool
+	}{
+		{db, DEBUG_LEVEL, true},
+		{db, TRACE_LEVEL, false},
+		{query, TRACE_LEVEL, true},
+		{http, DEBUG_LEVEL, false},
+		{http, INFO_LEVEL, true},
+	}
+
+	for _, c := range cases {
+		if c.record.enabled(c.level) != c.expected {
+			t.Errorf("expected %v for module %s at %v", c.expected, c.record.module, c.level)
+		}
+	}
+
+	buf.Reset()
+	db.Debug("Test Message")
+	if buf.Len() == 0 {
+		t.Error("debug message of module db is not printed")
+	}
+
+	buf.Reset()
+	logging.Debug("Test Message")
+	if buf.Len() != 0 {
+		t.Error("module level should not change the level of the logger")
+	}
+
+	// later changes reach existing module records
+	logging.RemoveModuleLevel("db.*")
+	logging.SetLevel(WARN_LEVEL)
+	if db.enabled(INFO_LEVEL) || http.enabled(INFO_LEVEL) {
+		t.Error("level change does not reach existing module records")
+	}
+
+	if !query.enabled(TRACE_LEVEL) {
+		t.Error("exact module level is lost")
+	}
+}
+
+func TestWildcardModuleLevel(t *testing.T) {
+	logging := NewLogging("test", INFO_LEVEL, 4)
+	logging.SetModuleLevel("*", ERROR_LEVEL)
+
+	if logging.levelOf("db") != ERROR_LEVEL {
+		t.Error("wildcard module level is not applied")
+	}
+
+	if logging.levelOf("") != INFO_LEVEL {
+		t.Error("wildcard module level should not apply to records without module")
+	}
+
+	for _, pattern := range []string{"", "db*", "*.db", "d*.x"} {
+		if err := logging.SetModuleLevel(pattern, DEBUG_LEVEL); err == nil {
+			t.Errorf("expected error for pattern %q", pattern)
+		}
+	}
+}
+
+func TestLevelOfWithoutLock(t *testing.T) {
+	logging := NewLogging("test", INFO_LEVEL, 4)
+	logging.SetModuleLevel("db.*", DEBUG_LEVEL)
+
+	// the level is read while mux is held, e.g. by a slow SetSinks
+	logging.mux.Lock()
+	levels := make(chan LoggingLevel, 1)
+	go func() {
+		levels <- logging.levelOf("db.query")
+	}()
+
+	select {
+	case level := <-levels:
+		if level != DEBUG_LEVEL {
+			t.Errorf("expected %v, got %v", DEBUG_LEVEL, level)
+		}
+	case <-time.After(time.Second):
+		t.Error("levelOf waits for mux")
+	}
+	logging.mux.Unlock()
+
+	logging.RemoveModuleLevel("db.*")
+	if logging.levelOf("db.query") != INFO_LEVEL {
+		t.Error("the removed module level is still applied")
+	}
+}
@@ -0,0 +1,270 @@
+package log
+
+import (
+	"encoding/binary"
+	"net"
+	"sync"
+	"time"
+)
+
+// Framing decides how the records are delimited on a stream
+type Framing int
+
+const (
+	// every record ends with '\n'
+	FRAMING_NEWLINE Framing = iota
+	// every record is preceded by its length as a 4-byte big-endian integer
+	FRAMING_LENGTH_PREFIX
+)
+
+type NetworkConfig struct {
+	// "tcp" or "unix"
+	Network string  `json:"network"`
+	Address string  `json:"address"`
+	Framing Framing `json:"framing"`
+	// max number of records kept in memory while disconnected, the oldest
+	// record is dropped when it is full
+	BufferSize int `json:"bufferSize"`
+	// the durations are strings such as "5s" in a config file
+	DialTimeout time.Duration `json:"-"`
+	// a write not done within WriteTimeout fails and the connection is
+	// reopened, so a stalled collector does not block the output
+	WriteTimeout time.Duration `json:"-"`
+	// the delay between two reconnections doubles from MinBackoff up to
+	// MaxBackoff
+	MinBackoff time.Duration `json:"-"`
+	MaxBackoff time.Duration `json:"-"`
+}
+
+const (
+	defaultNetworkBufferSize = 1024
+	defaultDialTimeout       = 5 * time.Second
+	defaultWriteTimeout      = 5 * time.Second
+	defaultMinBackoff        = 100 * time.Millisecond
+	defaultMaxBackoff        = 30 * time.Second
+)
+
+var _ OutPut = &NetworkOutput{}
+
+// NetworkOutput sends the records to a collector over a stream socket. The
+// writes never block on the network, the records are buffered and sent by a
+// background goroutine which reconnects with exponential backoff.
+type NetworkOutput struct {
+	NetworkConfig
+
+	mux     sync.Mutex
+	queue   [][]byte
+	dropped uint64
+	closed  bool
+
+	wake chan struct{}
+	exit chan struct{}
+	done chan struct{}
+}
+
+func NewNetworkOutput(config NetworkConfig) *NetworkOutput {
+	if config.BufferSize <= 0 {
+		config.BufferSize = defaultNetworkBufferSize
+	}
+
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = defaultDialTimeout
+	}
+
+	if config.WriteTimeout <= 0 {
+		config.WriteTimeout = defaultWriteTimeout
+	}
+
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = defaultMinBackoff
+	}
+
+	if config.MaxBackoff < config.MinBackoff {
+		config.MaxBackoff = defaultMaxBackoff
+		if config.MaxBackoff < config.MinBackoff {
+			config.MaxBackoff = config.MinBackoff
+		}
+	}
+
+	n := &NetworkOutput{
+		NetworkConfig: config,
+		wake:          make(chan struct{}, 1),
+		exit:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+
+	go n.run()
+
+	return n
+}
+
+// Write queues a copy of p, it never fails
+func (n *NetworkOutput) Write(p []byte) (int, error) {
+	record := make([]byte, len(p))
+	copy(record, p)
+
+	n.mux.Lock()
+	if n.closed {
+		n.dropped++
+		n.mux.Unlock()
+		return len(p), nil
+	}
+
+	if len(n.queue) >= n.BufferSize {
+		n.queue[0] = nil
+		n.queue = n.queue[1:]
+		n.dropped++
+	}
+	n.queue = append(n.queue, record)
+	n.mux.Unlock()
+
+	select {
+	case n.wake <- struct{}{}:
+	default:
+	}
+
+	return len(p), nil
+}
+
+func (n *NetworkOutput) Rotate() error {
+	return nil
+}
+
+// Dropped returns the number of records dropped because the buffer was full
+// or the output was closed
+func (n *NetworkOutput) Dropped() uint64 {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+
+	return n.dropped
+}
+
+// Close sends the buffered records if the collector is reachable and stops
+// the background goroutine
+func (n *NetworkOutput) Close() error {
+	n.mux.Lock()
+	if n.closed {
+		n.mux.Unlock()
+		return nil
+	}
+	n.closed = true
+	n.mux.Unlock()
+
+	close(n.exit)
+	<-n.done
+
+	n.mux.Lock()
+	n.dropped += uint64(len(n.queue))
+	n.queue = nil
+	n.mux.Unlock()
+
+	return nil
+}
+
+func (n *NetworkOutput) frame(record []byte) []byte {
+	if n.Framing == FRAMING_LENGTH_PREFIX {
+		framed := make([]byte, 4+len(record))
+		binary.BigEndian.PutUint32(framed, uint32(len(record)))
+		copy(framed[4:], record)
+		return framed
+	}
+
+	if len(record) == 0 || record[len(record)-1] != '\n' {
+		return append(record, '\n')
+	}
+
+	return record
+}
+
+// next returns the oldest queued record without removing it
+func (n *NetworkOutput) next() ([]byte, bool) {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+
+	if len(n.queue) == 0 {
+		return nil, false
+	}
+
+	return n.queue[0], true
+}
+
+func (n *NetworkOutput) pop() {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+
+	if len(n.queue) != 0 {
+		n.queue[0] = nil
+		n.queue = n.queue[1:]
+	}
+}
+
+// sleep waits for backoff and doubles it, it returns false when the output
+// is closed meanwhile
+func (n *NetworkOutput) sleep(backoff *time.Duration) bool {
+	timer := time.NewTimer(*backoff)
+	defer timer.Stop()
+
+	*backoff *= 2
+	if *backoff > n.MaxBackoff {
+		*backoff = n.MaxBackoff
+	}
+
+	select {
+	case <-timer.C:
+		return true
+	case <-n.exit:
+		return false
+	}
+}
+
+func (n *NetworkOutput) run() {
+	defer close(n.done)
+
+	var conn net.Conn
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+
+	backoff := n.MinBackoff
+
+	for {
+		if conn == nil {
+			c, err := net.DialTimeout(n.Network, n.Address, n.DialTimeout)
+			if err != nil {
+				if !n.sleep(&backoff) {
+					return
+				}
+				continue
+			}
+
+			conn = c
+			backoff = n.MinBackoff
+		}
+
+		record, ok := n.next()
+		if !ok {
+			select {
+			case <-n.wake:
+				continue
+			case <-n.exit:
+				return
+			}
+		}
+
+		conn.SetWriteDeadline(time.Now().Add(n.WriteTimeout))
+		if _, err := conn.Write(n.frame(record)); err != nil {
+			// the record stays queued and is sent after reconnecting, a
+			// timeout is handled like a broken connection
+			conn.Close()
+			conn = nil
+			if !n.sleep(&backoff) {
+				return
+			}
+			continue
+		}
+
+		n.pop()
+	}
+}
@@ -0,0 +1,185 @@
+package log
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNetworkOutputNewline(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer listener.Close()
+
+	output := NewNetworkOutput(NetworkConfig{Network: "tcp", Address: listener.Addr().String()})
+
+	logging := NewLogging("test", INFO_LEVEL, 4)
+	logging.SetOutPut(output)
+	logging.Info("first")
+	logging.Info("second")
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+
+	r := bufio.NewReader(conn)
+	for _, expected := range []string{"first", "second"} {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if !strings.HasSuffix(line, "Info msg: "+expected+"\n") {
+			t.Errorf("unexpected record %q", line)
+		}
+	}
+
+	output.Close()
+	if output.Dropped() != 0 {
+		t.Errorf("unexpected dropped records %d", output.Dropped())
+	}
+}
+
+func TestNetworkOutputReconnect(t *testing.T) {
+	// reserve an address nobody listens on yet
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	output := NewNetworkOutput(NetworkConfig{
+		Network:    "tcp",
+		Address:    address,
+		Framing:    FRAMING_LENGTH_PREFIX,
+		MinBackoff: 10 * time.Millisecond,
+		MaxBackoff: 50 * time.Millisecond,
+	})
+	defer output.Close()
+
+	output.Write([]byte("first"))
+	output.Write([]byte("second"))
+	time.Sleep(30 * time.Millisecond)
+
+	listener, err = net.Listen("tcp", address)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer listener.Close()
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+
+	for _, expected := range []string{"first", "second"} {
+		var length uint32
+		if err := binary.Read(conn, binary.BigEndian, &length); err != nil {
+			t.Error(err)
+			return
+		}
+
+		record := make([]byte, length)
+		if _, err := io.ReadFull(conn, record); err != nil {
+			t.Error(err)
+			return
+		}
+
+		if string(record) != expected {
+			t.Errorf("expected %q, got %q", expected, record)
+		}
+	}
+}
+
+func TestNetworkOutputDropped(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	output := NewNetworkOutput(NetworkConfig{
+		Network:    "tcp",
+		Address:    address,
+		BufferSize: 2,
+		MinBackoff: time.Hour,
+	})
+
+	for i := 0; i < 5; i++ {
+		output.Write([]byte("Test Message"))
+	}
+
+	if output.Dropped() != 3 {
+		t.Errorf("expected 3 dropped records, got %d", output.Dropped())
+	}
+
+	output.Close()
+	if output.Dropped() != 5 {
+		t.Errorf("expected 5 dropped records after close, got %d", output.Dropped())
+	}
+}
+
+func TestNetworkOutputStalled(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer listener.Close()
+
+	// the collector accepts the connection but never reads
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		<-done
+	}()
+
+	output := NewNetworkOutput(NetworkConfig{
+		Network:      "tcp",
+		Address:      listener.Addr().String(),
+		BufferSize:   8,
+		WriteTimeout: 50 * time.Millisecond,
+	})
+
+	record := make([]byte, 4<<20)
+	for i := 0; i < 8; i++ {
+		output.Write(record)
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	closed := make(chan struct{})
+	go func() {
+		output.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Error("Close is blocked by a stalled collector")
+	}
+}
@@ -5,9 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -16,6 +16,20 @@ type OutPut interface {
 	Rotate() error
 }
 
+// LevelWriter is implemented by the outputs which need the level of the
+// record they write, such as SyslogOutput
+type LevelWriter interface {
+	WriteLevel(level LoggingLevel, p []byte) (int, error)
+}
+
+func writeLevel(w io.Writer, level LoggingLevel, p []byte) (int, error) {
+	if lw, ok := w.(LevelWriter); ok {
+		return lw.WriteLevel(level, p)
+	}
+
+	return w.Write(p)
+}
+
 var _ OutPut = &BufferOutput{}
 
 // BufferOutput is use for unit test
@@ -27,15 +41,39 @@ func (b *BufferOutput) Rotate() error {
 	return nil
 }
 
+var _ OutPut = &WriterOutput{}
+
+// WriterOutput turns an io.Writer such as os.Stderr into an OutPut
+type WriterOutput struct {
+	io.Writer
+}
+
+func (w *WriterOutput) Rotate() error {
+	return nil
+}
+
 var _ OutPut = &FileOutput{}
 
 type FileOutput struct {
 	*os.File
 	fileName string
 	LogRotateConfig
+
+	compressWG sync.WaitGroup
+	// compressMux guards the files being compressed and the last
+	// compression failure
+	compressMux    sync.Mutex
+	compressing    map[string]bool
+	compressErr    error
+	nextRotate     time.Time
+	dryRunReported map[string]bool
 }
 
 func NewFileOutput(rotateConfig LogRotateConfig) (io.Writer, error) {
+	if _, err := parseRotateInterval(rotateConfig.RotateInterval); err != nil {
+		return nil, err
+	}
+
 	fileOutput := &FileOutput{
 		LogRotateConfig: rotateConfig,
 	}
@@ -47,8 +85,32 @@ func NewFileOutput(rotateConfig LogRotateConfig) (io.Writer, error) {
 	return fileOutput, nil
 }
 
+// Reconfigure applies cfg to a running FileOutput, a new file is opened when
+// FileDir, Prefix or UTC change. The current file and config are kept if it
+// fails
+func (f *FileOutput) Reconfigure(cfg LogRotateConfig) error {
+	if _, err := parseRotateInterval(cfg.RotateInterval); err != nil {
+		return err
+	}
+
+	old := f.LogRotateConfig
+	f.LogRotateConfig = cfg
+
+	if cfg.FileDir == old.FileDir && cfg.Prefix == old.Prefix && cfg.UTC == old.UTC {
+		f.updateNextRotateTime(f.now())
+		return nil
+	}
+
+	if err := f.generateFile(); err != nil {
+		f.LogRotateConfig = old
+		return err
+	}
+
+	return nil
+}
+
 func (f *FileOutput) parseFileTime(fileName string) (int64, error) {
-	t, err := time.ParseInLocation(fmt.Sprintf("%s_20060102_150405.log", f.Prefix), fileName, time.Local)
+	t, err := time.ParseInLocation(fmt.Sprintf("%s_20060102_150405.log", f.Prefix), fileName, f.location())
 	if err != nil {
 		return 0, err
 	}
@@ -57,6 +119,14 @@ func (f *FileOutput) parseFileTime(fileName string) (int64, error) {
 }
 
 func (f *FileOutput) parseFileName(fileName string) (bool, int64, error) {
+	// compressed logs are named like prefix_20060102_150405.log.gz
+	for _, extension := range codecExtensions() {
+		if strings.HasSuffix(fileName, ".log"+extension) {
+			fileName = strings.TrimSuffix(fileName, extension)
+			break
+		}
+	}
+
 	if !(strings.HasSuffix(fileName, ".log") && strings.HasPrefix(fileName, f.Prefix)) {
 		return false, 0, fmt.Errorf("invalid log file")
 	}
@@ -70,41 +140,27 @@ func (f *FileOutput) parseFileName(fileName string) (bool, int64, error) {
 }
 
 func (f *FileOutput) getAllLogs() (map[string]int64, error) {
-	fileInfos, err := ioutil.ReadDir(f.FileDir)
+	logs, err := f.listLogs()
 	if err != nil {
 		return nil, err
 	}
 
 	ret := map[string]int64{}
-
-	for _, fileInfo := range fileInfos {
-		name := fileInfo.Name()
-
-		isLogFile, ts, err := f.parseFileName(name)
-		if err != nil || !isLogFile {
-			continue
-		}
-
-		ret[name] = ts
+	for _, log := range logs {
+		ret[log.name] = log.ts
 	}
 
 	return ret, nil
 }
 
 func (f *FileOutput) cleanExpiredLogs(now int64) error {
-	logs, err := f.getAllLogs()
+	paths, err := f.ApplyRetention(time.Unix(now, 0))
 	if err != nil {
 		return err
 	}
 
-	for logName, ts := range logs {
-		if logName == f.fileName {
-			continue
-		}
-
-		if ts+f.MaxLogLife < now {
-			os.Remove(logName)
-		}
+	if f.DryRun {
+		f.reportDryRun(paths)
 	}
 
 	return nil
@@ -120,23 +176,43 @@ func (f *FileOutput) generateFileWithTime(t time.Time) error {
 		}
 	}
 
-	if f.File != nil {
-		f.File.Close()
-	}
-
 	file, err := os.OpenFile(logFile, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0666)
 	if err != nil {
 		return err
 	}
 
+	// the current file is closed only once the new one is open, so a
+	// failure keeps the output usable
+	if f.File != nil {
+		f.File.Close()
+	}
+
+	oldFileName := f.fileName
 	f.fileName = logFile
 	f.File = file
+	f.updateNextRotateTime(t)
+
+	if f.Compress != "" && oldFileName != "" && oldFileName != logFile {
+		f.compressInBackground(oldFileName)
+	}
 
 	return nil
 }
 
+// Close closes the current file and waits for the rotated files to be
+// compressed
+func (f *FileOutput) Close() error {
+	f.compressWG.Wait()
+
+	if f.File == nil {
+		return nil
+	}
+
+	return f.File.Close()
+}
+
 func (f *FileOutput) generateFile() error {
-	return f.generateFileWithTime(time.Now().Local())
+	return f.generateFileWithTime(f.now())
 }
 
 func (f *FileOutput) checkLogFileSize() (bool, error) {
@@ -149,6 +225,11 @@ func (f *FileOutput) checkLogFileSize() (bool, error) {
 		return false, err
 	}
 
+	// with a rotate interval and no MaxSize, the file is only rotated by time
+	if f.MaxSize <= 0 && f.rotateInterval() != 0 {
+		return false, nil
+	}
+
 	return fileInfo.Size() > f.MaxSize*1024*1024, nil
 }
 
@@ -158,13 +239,17 @@ func (f *FileOutput) Rotate() error {
 		return err
 	}
 
-	t := time.Now().Local()
+	t := f.now()
 
-	if needNewFile {
+	if needNewFile || f.rotateTimeReached(t) {
 		if err := f.generateFileWithTime(t); err != nil {
 			return err
 		}
 	}
 
-	return f.cleanExpiredLogs(t.Unix())
+	if err := f.cleanExpiredLogs(t.Unix()); err != nil {
+		return err
+	}
+
+	return f.compressError()
 }
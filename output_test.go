@@ -19,6 +19,17 @@ func cleanLogs() {
 	}
 }
 
+func cleanCompressedLogs() {
+	files, err := filepath.Glob("*.log.gz")
+	if err != nil {
+		panic(err)
+	}
+
+	for _, file := range files {
+		os.Remove(file)
+	}
+}
+
 func TestGenerateLog(t *testing.T) {
 	fileOutput := FileOutput{
 		LogRotateConfig: LogRotateConfig{
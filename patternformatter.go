@@ -0,0 +1,336 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// the verbs of a pattern
+const (
+	verbLiteral = iota
+	verbTime
+	verbLevel
+	verbModule
+	verbFile
+	verbLine
+	verbCaller
+	verbFunc
+	verbMsg
+	verbError
+	verbFields
+	verbStack
+)
+
+var patternVerbs = map[string]int{
+	"time":   verbTime,
+	"level":  verbLevel,
+	"module": verbModule,
+	"file":   verbFile,
+	"line":   verbLine,
+	"caller": verbCaller,
+	"func":   verbFunc,
+	"msg":    verbMsg,
+	"error":  verbError,
+	"fields": verbFields,
+	"stack":  verbStack,
+}
+
+var timeLayouts = map[string]string{
+	"ANSIC":       time.ANSIC,
+	"RFC822":      time.RFC822,
+	"RFC822Z":     time.RFC822Z,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"Kitchen":     time.Kitchen,
+	"Stamp":       time.Stamp,
+	"StampMilli":  time.StampMilli,
+	"StampMicro":  time.StampMicro,
+	"StampNano":   time.StampNano,
+}
+
+// patternPart is a literal text or a verb of a compiled pattern
+type patternPart struct {
+	verb    int
+	literal string
+	// the value is padded with spaces up to width, on the right when left
+	// is set
+	width int
+	left  bool
+
+	// the layout of time, CacheTime is used when it is empty
+	timeLayout string
+	// the name of every level for level
+	levels [FATAL_LEVEL + 1]string
+	// the path segments kept by file and caller, 0 keeps the full path
+	segments int
+}
+
+// PatternFormatter returns a Formatter laying out the records as described
+// by pattern, e.g.
+//
+//	%time{RFC3339} [%-5level{upper}] %module %file:%line %msg %fields
+//
+// The verbs are %time{layout}, %level{upper|lower}, %module, %file{segments},
+// %line, %caller{segments}, %func, %msg, %error, %fields and %stack. The
+// layout of time is either the name of a layout of the time package or a Go
+// layout, and segments is the number of trailing path segments kept. The
+// caller verbs are empty when the caller is disabled, and %stack writes the
+// stack trace as a block of lines when the record has one. A width such as
+// %5level pads the value on the left, %-5level pads it on the right. %%
+// writes a '%', the rest of the pattern is written as is and a newline ends
+// every record. The pattern is compiled once, when the formatter is created
+func PatternFormatter(pattern string) (Formatter, error) {
+	parts, err := compilePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(logRecord *LogRecord) *bytes.Buffer {
+		caller, line := logRecord.caller()
+
+		buf := pool.Get()
+		buf.Reset()
+
+		for i := range parts {
+			parts[i].write(buf, logRecord, caller, line)
+		}
+		buf.WriteString("\n")
+
+		return buf
+	}, nil
+}
+
+func compilePattern(pattern string) ([]patternPart, error) {
+	var (
+		parts   []patternPart
+		literal strings.Builder
+	)
+
+	flushLiteral := func() {
+		if literal.Len() != 0 {
+			parts = append(parts, patternPart{verb: verbLiteral, literal: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(pattern); {
+		if pattern[i] != '%' {
+			literal.WriteByte(pattern[i])
+			i++
+			continue
+		}
+
+		start := i
+		i++
+		if i < len(pattern) && pattern[i] == '%' {
+			literal.WriteByte('%')
+			i++
+			continue
+		}
+
+		part := patternPart{}
+
+		if i < len(pattern) && pattern[i] == '-' {
+			part.left = true
+			i++
+		}
+
+		j := i
+		for j < len(pattern) && pattern[j] >= '0' && pattern[j] <= '9' {
+			j++
+		}
+		if j > i {
+			part.width, _ = strconv.Atoi(pattern[i:j])
+		}
+		i = j
+
+		for j < len(pattern) && pattern[j] >= 'a' && pattern[j] <= 'z' {
+			j++
+		}
+		name := pattern[i:j]
+		i = j
+
+		verb, ok := patternVerbs[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown verb %q at %d in pattern %q", pattern[start:i], start, pattern)
+		}
+		part.verb = verb
+
+		arg := ""
+		hasArg := false
+		if i < len(pattern) && pattern[i] == '{' {
+			end := strings.IndexByte(pattern[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed '{' at %d in pattern %q", i, pattern)
+			}
+
+			arg = pattern[i+1 : i+end]
+			hasArg = true
+			i += end + 1
+		}
+
+		if err := part.setArg(arg, hasArg); err != nil {
+			return nil, fmt.Errorf("%%%s at %d in pattern %q: %v", name, start, pattern, err)
+		}
+
+		flushLiteral()
+		parts = append(parts, part)
+	}
+
+	flushLiteral()
+
+	return parts, nil
+}
+
+func (p *patternPart) setArg(arg string, hasArg bool) error {
+	switch p.verb {
+	case verbTime:
+		if !hasArg {
+			return nil
+		}
+
+		if arg == "" {
+			return fmt.Errorf("empty time layout")
+		}
+
+		p.timeLayout = arg
+		if layout, ok := timeLayouts[arg]; ok {
+			p.timeLayout = layout
+		}
+	case verbLevel:
+		for level := TRACE_LEVEL; level <= FATAL_LEVEL; level++ {
+			switch arg {
+			case "":
+				p.levels[level] = level.String()
+			case "upper":
+				p.levels[level] = strings.ToUpper(level.String())
+			case "lower":
+				p.levels[level] = strings.ToLower(level.String())
+			default:
+				return fmt.Errorf("unknown case %q, expected \"upper\" or \"lower\"", arg)
+			}
+		}
+	case verbFile, verbCaller:
+		if !hasArg {
+			return nil
+		}
+
+		segments, err := strconv.Atoi(arg)
+		if err != nil || segments <= 0 {
+			return fmt.Errorf("invalid number of path segments %q", arg)
+		}
+		p.segments = segments
+	default:
+		if hasArg {
+			return fmt.Errorf("no argument expected")
+		}
+	}
+
+	return nil
+}
+
+func (p *patternPart) file(caller string) string {
+	if p.segments > 0 {
+		return shortCaller(caller, p.segments)
+	}
+
+	return caller
+}
+
+func (p *patternPart) write(buf *bytes.Buffer, logRecord *LogRecord, caller string, line int) {
+	start := buf.Len()
+
+	switch p.verb {
+	case verbLiteral:
+		buf.WriteString(p.literal)
+	case verbTime:
+		if p.timeLayout == "" {
+			buf.WriteString(formatCacheTime(logRecord.timestamp()))
+		} else {
+			buf.WriteString(logRecord.timestamp().Format(p.timeLayout))
+		}
+	case verbLevel:
+		if logRecord.logLevel >= TRACE_LEVEL && logRecord.logLevel <= FATAL_LEVEL {
+			buf.WriteString(p.levels[logRecord.logLevel])
+		} else {
+			buf.WriteString(logRecord.logLevel.String())
+		}
+	case verbModule:
+		buf.WriteString(logRecord.module)
+	case verbFile:
+		buf.WriteString(p.file(caller))
+	case verbLine:
+		if logRecord.enableCaller {
+			buf.WriteString(strconv.Itoa(line))
+		}
+	case verbCaller:
+		if logRecord.enableCaller {
+			buf.WriteString(p.file(caller))
+			buf.WriteString(":")
+			buf.WriteString(strconv.Itoa(line))
+		}
+	case verbFunc:
+		buf.WriteString(logRecord.function)
+	case verbMsg:
+		if len(logRecord.format) == 0 {
+			fmt.Fprint(buf, logRecord.args...)
+		} else {
+			fmt.Fprintf(buf, logRecord.format, logRecord.args...)
+		}
+	case verbError:
+		if logRecord.err != nil {
+			buf.WriteString(logRecord.err.Error())
+		}
+	case verbFields:
+		for i, field := range logRecord.fields {
+			if i > 0 {
+				buf.WriteString(" ")
+			}
+			writeLogfmtKey(buf, field.Key)
+			buf.WriteString("=")
+			writeLogfmtValue(buf, field.Value)
+		}
+	case verbStack:
+		if len(logRecord.stack) != 0 {
+			buf.WriteString("\n")
+			writeStack(buf, logRecord.stack)
+			// the newline ending the record follows
+			buf.Truncate(buf.Len() - 1)
+		}
+	}
+
+	if p.width > 0 {
+		pad(buf, start, p.width, p.left)
+	}
+}
+
+// pad pads the bytes written to buf since start with spaces up to width
+// runes
+func pad(buf *bytes.Buffer, start, width int, left bool) {
+	n := width - utf8.RuneCount(buf.Bytes()[start:])
+	if n <= 0 {
+		return
+	}
+
+	end := buf.Len()
+	for i := 0; i < n; i++ {
+		buf.WriteByte(' ')
+	}
+
+	if left {
+		return
+	}
+
+	// move the value to the right of the spaces
+	b := buf.Bytes()
+	copy(b[start+n:], b[start:end])
+	for i := start; i < start+n; i++ {
+		b[i] = ' '
+	}
+}
@@ -0,0 +1,119 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPatternFormatter(t *testing.T) {
+	cases := []struct {
+		pattern  string
+		record   *LogRecord
+		expected string
+	}{
+		{
+			"[%-5level{upper}] %module %msg",
+			&LogRecord{logLevel: INFO_LEVEL, module: "db", args: []interface{}{"started"}},
+			"[INFO ] db started\n",
+		},
+		{
+			"%5level{lower}|%-4module|%msg %fields",
+			&LogRecord{logLevel: WARN_LEVEL, module: "db", format: "took %dms", args: []interface{}{12}, fields: []Field{{"id", 7}, {"user", "a b"}}},
+			" warn|db  |took 12ms id=7 user=\"a b\"\n",
+		},
+		{
+			"%file{1}:%line 100%% %msg: %error",
+			&LogRecord{logLevel: ERROR_LEVEL, args: []interface{}{"failed"}, err: errors.New("timeout"), enableCaller: true, callerResolved: true, file: "/src/log/output.go", line: 12},
+			"output.go:12 100% failed: timeout\n",
+		},
+		{
+			"%caller{2} %6msg",
+			&LogRecord{logLevel: INFO_LEVEL, args: []interface{}{"é"}, enableCaller: true, callerResolved: true, file: "/src/log/output.go", line: 3},
+			"log/output.go:3      é\n",
+		},
+		{
+			"%time{2006} %level",
+			&LogRecord{logLevel: DEBUG_LEVEL},
+			" Debug\n",
+		},
+	}
+
+	for _, c := range cases {
+		formatter, err := PatternFormatter(c.pattern)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		s := formatter(c.record).String()
+		if strings.HasPrefix(c.pattern, "%time") {
+			// the year is not stable
+			s = s[4:]
+		}
+
+		if s != c.expected {
+			t.Errorf("expected %q, got %q", c.expected, s)
+		}
+	}
+}
+
+func TestPatternFormatterErrors(t *testing.T) {
+	patterns := []string{
+		"%date",
+		"%level{title}",
+		"%msg{x}",
+		"%time{RFC3339",
+		"%file{0}",
+		"%",
+	}
+
+	for _, pattern := range patterns {
+		if _, err := PatternFormatter(pattern); err == nil {
+			t.Errorf("expected an error for %q", pattern)
+		}
+	}
+}
+
+func TestPatternFormatterLogging(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	formatter, err := PatternFormatter("%level %file{1}:%line %msg")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	logging := NewLogging("test", INFO_LEVEL, 4)
+	logging.SetOutPut(buf)
+	logging.SetFormatter(formatter)
+	logging.Info("hello")
+
+	if !strings.HasPrefix(buf.String(), "Info ") || !strings.HasSuffix(buf.String(), " hello\n") {
+		t.Errorf("unexpected record %q", buf.String())
+	}
+}
+
+func BenchmarkPatternFormatter(b *testing.B) {
+	formatter, err := PatternFormatter("%time [%-5level{upper}] %-8module %caller %msg %fields")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	record := &LogRecord{
+		logLevel:       INFO_LEVEL,
+		module:         "db",
+		args:           []interface{}{"query"},
+		fields:         []Field{{"rows", 12}},
+		callerResolved: true,
+		file:           "/src/log/output.go",
+		line:           12,
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pool.Put(formatter(record))
+	}
+}
@@ -0,0 +1,76 @@
+package log
+
+import (
+	"sort"
+	"sync"
+)
+
+var (
+	registryMux sync.RWMutex
+	registry    = map[string]*logging{}
+)
+
+// Register makes l visible to the level handler under name
+func Register(name string, l *logging) {
+	registryMux.Lock()
+	defer registryMux.Unlock()
+
+	registry[name] = l
+}
+
+func Unregister(name string) {
+	registryMux.Lock()
+	defer registryMux.Unlock()
+
+	delete(registry, name)
+}
+
+// Lookup returns the logging registered under name
+func Lookup(name string) (*logging, bool) {
+	registryMux.RLock()
+	defer registryMux.RUnlock()
+
+	l, ok := registry[name]
+	return l, ok
+}
+
+func registeredNames() []string {
+	registryMux.RLock()
+	defer registryMux.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func (l *logging) addModule(module string) {
+	if module == "" {
+		return
+	}
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if l.modules == nil {
+		l.modules = map[string]struct{}{}
+	}
+	l.modules[module] = struct{}{}
+}
+
+// Modules returns the names of the modules used with l
+func (l *logging) Modules() []string {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	modules := make([]string, 0, len(l.modules))
+	for module := range l.modules {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+
+	return modules
+}
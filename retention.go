@@ -0,0 +1,156 @@
+package log
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// logFile is a rotated log file found in FileDir
+type logFile struct {
+	name string
+	path string
+	ts   int64
+	size int64
+}
+
+func (f *FileOutput) listLogs() ([]logFile, error) {
+	fileInfos, err := ioutil.ReadDir(f.FileDir)
+	if err != nil {
+		return nil, err
+	}
+
+	logs := []logFile{}
+
+	for _, fileInfo := range fileInfos {
+		name := fileInfo.Name()
+
+		isLogFile, ts, err := f.parseFileName(name)
+		if err != nil || !isLogFile {
+			continue
+		}
+
+		path := joinFilePath(f.FileDir, name)
+		// counted once it is compressed
+		if f.isCompressing(path) {
+			continue
+		}
+
+		logs = append(logs, logFile{
+			name: name,
+			path: path,
+			ts:   ts,
+			size: fileInfo.Size(),
+		})
+	}
+
+	return logs, nil
+}
+
+// planRetention returns the files which break the retention policy. The
+// current file is never returned, neither are the newest MinKeep files.
+func (f *FileOutput) planRetention(logs []logFile, now int64) []logFile {
+	var (
+		current   int64
+		rotated   []logFile
+		remove    []logFile
+		totalSize int64
+		kept      int
+		// MaxTotalSize was exceeded, every older file is removed
+		overflowed bool
+	)
+
+	currentPath := filepath.Clean(f.fileName)
+	for _, log := range logs {
+		if log.path == currentPath {
+			current = log.size
+			continue
+		}
+
+		rotated = append(rotated, log)
+	}
+
+	// newest first, so the oldest files are removed first
+	sort.Slice(rotated, func(i, j int) bool {
+		if rotated[i].ts != rotated[j].ts {
+			return rotated[i].ts > rotated[j].ts
+		}
+
+		return rotated[i].name > rotated[j].name
+	})
+
+	totalSize = current
+	for i, log := range rotated {
+		expired := f.MaxLogLife > 0 && log.ts+f.MaxLogLife < now
+		tooMany := f.MaxBackups > 0 && kept >= f.MaxBackups
+		tooBig := f.MaxTotalSize > 0 && (overflowed || totalSize+log.size > f.MaxTotalSize*1024*1024)
+		overflowed = overflowed || tooBig
+
+		if i >= f.MinKeep && (expired || tooMany || tooBig) {
+			remove = append(remove, log)
+			continue
+		}
+
+		kept++
+		totalSize += log.size
+	}
+
+	return remove
+}
+
+// ApplyRetention removes, or moves to ArchiveDir, the rotated files which
+// break the retention policy and returns their paths. With DryRun set
+// nothing is touched, the returned paths are the ones which would be
+// removed.
+func (f *FileOutput) ApplyRetention(now time.Time) ([]string, error) {
+	logs, err := f.listLogs()
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{}
+	for _, log := range f.planRetention(logs, now.Unix()) {
+		if !f.DryRun {
+			if err := f.retire(log); err != nil {
+				return paths, err
+			}
+		}
+
+		paths = append(paths, log.path)
+	}
+
+	return paths, nil
+}
+
+func (f *FileOutput) retire(log logFile) error {
+	if f.ArchiveDir == "" {
+		return os.Remove(log.path)
+	}
+
+	if !isExist(f.ArchiveDir) {
+		if err := os.MkdirAll(f.ArchiveDir, 0755); err != nil {
+			return err
+		}
+	}
+
+	return os.Rename(log.path, joinFilePath(f.ArchiveDir, log.name))
+}
+
+// reportDryRun prints every file the retention would remove once
+func (f *FileOutput) reportDryRun(paths []string) {
+	if f.dryRunReported == nil {
+		f.dryRunReported = map[string]bool{}
+	}
+
+	for _, path := range paths {
+		if f.dryRunReported[path] {
+			continue
+		}
+
+		f.dryRunReported[path] = true
+		fmt.Fprintf(os.Stderr, "log: retention would remove %s\n", path)
+	}
+}
@@ -0,0 +1,195 @@
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+// createLogs creates a log file for every age in seconds, the newest one is
+// the current file, every file holds size bytes
+func createLogs(t *testing.T, fileOutput *FileOutput, now time.Time, ages []int, size int) []string {
+	sort.Sort(sort.Reverse(sort.IntSlice(ages)))
+
+	names := []string{}
+	for _, age := range ages {
+		if err := fileOutput.generateFileWithTime(now.Add(-time.Duration(age) * time.Second)); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := fileOutput.Write(make([]byte, size)); err != nil {
+			t.Fatal(err)
+		}
+
+		names = append(names, fileOutput.fileName)
+	}
+
+	return names
+}
+
+func remainingLogs(t *testing.T, dir string) []string {
+	files, err := filepath.Glob(filepath.Join(dir, "log_*.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(files)
+	return files
+}
+
+func newRetentionOutput(t *testing.T, config LogRotateConfig) (*FileOutput, string) {
+	dir, err := ioutil.TempDir("", "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config.EnableLogFile = true
+	config.Prefix = "log"
+	config.FileDir = dir
+
+	return &FileOutput{LogRotateConfig: config}, dir
+}
+
+func TestRetentionMaxLogLife(t *testing.T) {
+	fileOutput, dir := newRetentionOutput(t, LogRotateConfig{MaxLogLife: 60})
+	defer os.RemoveAll(dir)
+	defer fileOutput.Close()
+
+	now := time.Now()
+	names := createLogs(t, fileOutput, now, []int{90, 70, 50, 10}, 1)
+
+	removed, err := fileOutput.ApplyRetention(now)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(removed) != 2 {
+		t.Errorf("expected 2 removed logs, got %v", removed)
+	}
+
+	if remaining := remainingLogs(t, dir); len(remaining) != 2 || remaining[0] != names[2] || remaining[1] != names[3] {
+		t.Errorf("unexpected remaining logs %v", remaining)
+	}
+}
+
+func TestRetentionMaxBackups(t *testing.T) {
+	fileOutput, dir := newRetentionOutput(t, LogRotateConfig{MaxBackups: 2})
+	defer os.RemoveAll(dir)
+	defer fileOutput.Close()
+
+	now := time.Now()
+	names := createLogs(t, fileOutput, now, []int{40, 30, 20, 10, 0}, 1)
+
+	if _, err := fileOutput.ApplyRetention(now); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if remaining := remainingLogs(t, dir); len(remaining) != 3 || remaining[0] != names[2] {
+		t.Errorf("unexpected remaining logs %v", remaining)
+	}
+}
+
+func TestRetentionMaxTotalSize(t *testing.T) {
+	fileOutput, dir := newRetentionOutput(t, LogRotateConfig{MaxTotalSize: 1})
+	defer os.RemoveAll(dir)
+	defer fileOutput.Close()
+
+	now := time.Now()
+	names := createLogs(t, fileOutput, now, []int{30, 20, 10, 0}, 400*1024)
+
+	if _, err := fileOutput.ApplyRetention(now); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if remaining := remainingLogs(t, dir); len(remaining) != 2 || remaining[0] != names[2] {
+		t.Errorf("unexpected remaining logs %v", remaining)
+	}
+}
+
+func TestRetentionMaxTotalSizeOlder(t *testing.T) {
+	fileOutput := &FileOutput{LogRotateConfig: LogRotateConfig{MaxTotalSize: 1}}
+
+	// the oldest file would fit once the second one is removed, it is
+	// removed anyway
+	logs := []logFile{
+		{name: "log_3.log", ts: 3, size: 600 * 1024},
+		{name: "log_2.log", ts: 2, size: 600 * 1024},
+		{name: "log_1.log", ts: 1, size: 100 * 1024},
+	}
+
+	remove := fileOutput.planRetention(logs, 4)
+	if len(remove) != 2 || remove[0].name != "log_2.log" || remove[1].name != "log_1.log" {
+		t.Errorf("unexpected removed logs %v", remove)
+	}
+}
+
+func TestRetentionMinKeep(t *testing.T) {
+	fileOutput, dir := newRetentionOutput(t, LogRotateConfig{MaxLogLife: 60, MinKeep: 2})
+	defer os.RemoveAll(dir)
+	defer fileOutput.Close()
+
+	now := time.Now()
+	createLogs(t, fileOutput, now, []int{300, 200, 100, 0}, 1)
+
+	if _, err := fileOutput.ApplyRetention(now); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if remaining := remainingLogs(t, dir); len(remaining) != 3 {
+		t.Errorf("unexpected remaining logs %v", remaining)
+	}
+}
+
+func TestRetentionArchive(t *testing.T) {
+	fileOutput, dir := newRetentionOutput(t, LogRotateConfig{MaxBackups: 1})
+	defer os.RemoveAll(dir)
+	defer fileOutput.Close()
+
+	fileOutput.ArchiveDir = filepath.Join(dir, "archive")
+
+	now := time.Now()
+	names := createLogs(t, fileOutput, now, []int{20, 10, 0}, 1)
+
+	if _, err := fileOutput.ApplyRetention(now); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if remaining := remainingLogs(t, dir); len(remaining) != 2 {
+		t.Errorf("unexpected remaining logs %v", remaining)
+	}
+
+	if !isExist(filepath.Join(fileOutput.ArchiveDir, filepath.Base(names[0]))) {
+		t.Error("log is not moved to the archive directory")
+	}
+}
+
+func TestRetentionDryRun(t *testing.T) {
+	fileOutput, dir := newRetentionOutput(t, LogRotateConfig{MaxBackups: 1, DryRun: true})
+	defer os.RemoveAll(dir)
+	defer fileOutput.Close()
+
+	now := time.Now()
+	names := createLogs(t, fileOutput, now, []int{20, 10, 0}, 1)
+
+	removed, err := fileOutput.ApplyRetention(now)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(removed) != 1 || removed[0] != names[0] {
+		t.Errorf("unexpected dry run result %v", removed)
+	}
+
+	if remaining := remainingLogs(t, dir); len(remaining) != 3 {
+		t.Errorf("dry run removed logs %v", remaining)
+	}
+}
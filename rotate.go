@@ -0,0 +1,87 @@
+package log
+
+import (
+	"fmt"
+	"time"
+)
+
+// parseRotateInterval parses LogRotateConfig.RotateInterval, an empty
+// string means the files are not rotated by time
+func parseRotateInterval(s string) (time.Duration, error) {
+	switch s {
+	case "":
+		return 0, nil
+	case "hourly":
+		return time.Hour, nil
+	case "daily":
+		return 24 * time.Hour, nil
+	}
+
+	interval, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rotate interval %q: %v", s, err)
+	}
+
+	// the file names only have a resolution of one second
+	if interval < time.Second {
+		return 0, fmt.Errorf("invalid rotate interval %q: less than one second", s)
+	}
+
+	return interval, nil
+}
+
+// nextRotateTime returns the first boundary of interval after t, the
+// boundaries are aligned to the wall clock of loc, e.g. an hourly interval
+// rotates at the start of every hour and a daily one at midnight
+func nextRotateTime(t time.Time, interval time.Duration, loc *time.Location) time.Time {
+	t = t.In(loc)
+
+	if interval%(24*time.Hour) == 0 {
+		days := int(interval / (24 * time.Hour))
+		midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		return midnight.AddDate(0, 0, days)
+	}
+
+	_, offset := t.Zone()
+	shift := time.Duration(offset) * time.Second
+
+	return t.Add(shift).Truncate(interval).Add(interval).Add(-shift)
+}
+
+func (f *FileOutput) location() *time.Location {
+	if f.UTC {
+		return time.UTC
+	}
+
+	return time.Local
+}
+
+func (f *FileOutput) now() time.Time {
+	return time.Now().In(f.location())
+}
+
+// rotateInterval returns 0 when RotateInterval is empty or invalid, an
+// invalid value is reported by NewFileOutput
+func (f *FileOutput) rotateInterval() time.Duration {
+	interval, err := parseRotateInterval(f.RotateInterval)
+	if err != nil {
+		return 0
+	}
+
+	return interval
+}
+
+func (f *FileOutput) updateNextRotateTime(t time.Time) {
+	interval := f.rotateInterval()
+	if interval == 0 {
+		f.nextRotate = time.Time{}
+		return
+	}
+
+	f.nextRotate = nextRotateTime(t, interval, f.location())
+}
+
+// rotateTimeReached reports whether the time based rotation is due
+func (f *FileOutput) rotateTimeReached(t time.Time) bool {
+	return !f.nextRotate.IsZero() && !t.Before(f.nextRotate)
+}
@@ -0,0 +1,144 @@
+package log
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRotateInterval(t *testing.T) {
+	cases := map[string]time.Duration{
+		"":       0,
+		"hourly": time.Hour,
+		"daily":  24 * time.Hour,
+		"15m":    15 * time.Minute,
+	}
+
+	for s, expected := range cases {
+		interval, err := parseRotateInterval(s)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+
+		if interval != expected {
+			t.Errorf("expected %v for %q, got %v", expected, s, interval)
+		}
+	}
+
+	for _, s := range []string{"weekly", "10ms", "-1h"} {
+		if _, err := parseRotateInterval(s); err == nil {
+			t.Errorf("expected error for %q", s)
+		}
+	}
+}
+
+func TestNextRotateTime(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	now := time.Date(2020, 11, 8, 11, 40, 53, 0, loc)
+
+	cases := []struct {
+		interval time.Duration
+		loc      *time.Location
+		expected time.Time
+	}{
+		{time.Hour, loc, time.Date(2020, 11, 8, 12, 0, 0, 0, loc)},
+		{15 * time.Minute, loc, time.Date(2020, 11, 8, 11, 45, 0, 0, loc)},
+		{24 * time.Hour, loc, time.Date(2020, 11, 9, 0, 0, 0, 0, loc)},
+		{24 * time.Hour, time.UTC, time.Date(2020, 11, 9, 0, 0, 0, 0, time.UTC)},
+		{6 * time.Hour, time.UTC, time.Date(2020, 11, 8, 6, 0, 0, 0, time.UTC)},
+	}
+
+	for _, c := range cases {
+		next := nextRotateTime(now, c.interval, c.loc)
+		if !next.Equal(c.expected) {
+			t.Errorf("expected %v for %v in %v, got %v", c.expected, c.interval, c.loc, next)
+		}
+	}
+}
+
+func TestRotateByTime(t *testing.T) {
+	fileOutput := FileOutput{
+		LogRotateConfig: LogRotateConfig{
+			EnableLogFile:  true,
+			Prefix:         "log",
+			FileDir:        ".",
+			MaxSize:        1,
+			MaxLogLife:     24 * 3600,
+			RotateInterval: "hourly",
+		},
+	}
+	defer cleanLogs()
+
+	if err := fileOutput.generateFileWithTime(time.Now().Add(-2 * time.Hour)); err != nil {
+		t.Error(err)
+		return
+	}
+	oldFileName := fileOutput.fileName
+
+	if err := fileOutput.Rotate(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if fileOutput.fileName == oldFileName {
+		t.Error("log file is not rotated by time")
+		return
+	}
+
+	newFileName := fileOutput.fileName
+	if err := fileOutput.Rotate(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if fileOutput.fileName != newFileName {
+		t.Error("log file is rotated before the next boundary")
+	}
+}
+
+func TestRotateByTimeOnly(t *testing.T) {
+	fileOutput := FileOutput{
+		LogRotateConfig: LogRotateConfig{
+			EnableLogFile:  true,
+			Prefix:         "log",
+			FileDir:        ".",
+			RotateInterval: "daily",
+		},
+	}
+	defer cleanLogs()
+
+	if err := fileOutput.generateFileWithTime(time.Now().Add(-2 * time.Second)); err != nil {
+		t.Error(err)
+		return
+	}
+	defer fileOutput.Close()
+	fileName := fileOutput.fileName
+
+	if _, err := fileOutput.WriteString("hello\n"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := fileOutput.Rotate(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// a MaxSize of 0 is no size limit
+	if fileOutput.fileName != fileName {
+		t.Error("log file is rotated by size")
+	}
+}
+
+func TestInvalidRotateInterval(t *testing.T) {
+	_, err := NewFileOutput(LogRotateConfig{
+		EnableLogFile:  true,
+		Prefix:         "log",
+		FileDir:        ".",
+		RotateInterval: "weekly",
+	})
+
+	if err == nil {
+		t.Error("expected error for invalid rotate interval")
+	}
+}
@@ -0,0 +1,174 @@
+package log
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SampleRate keeps the first First records with the same message in an
+// interval, then every Thereafter-th one. A zero Thereafter drops every
+// record after the first First, a negative First keeps every record
+type SampleRate struct {
+	First      int `json:"first"`
+	Thereafter int `json:"thereafter"`
+}
+
+// NoSampling is the rate of the levels whose records are all kept
+var NoSampling = SampleRate{First: -1}
+
+type SamplerConfig struct {
+	// 1 second if it is not set
+	Interval time.Duration `json:"interval"`
+	// the rate of the levels missing in Levels, 100 and 100 if it is not
+	// set
+	SampleRate
+	// ERROR_LEVEL and FATAL_LEVEL use NoSampling unless they are set here
+	Levels map[LoggingLevel]SampleRate `json:"levels"`
+}
+
+const defaultSampleInterval = time.Second
+
+var defaultSampleRate = SampleRate{First: 100, Thereafter: 100}
+
+// sampleKey identifies the records counted together
+type sampleKey struct {
+	level   LoggingLevel
+	module  string
+	message string
+}
+
+// Sampler limits the records logged with the same message, level and
+// module in every interval. It is shared by the loggers and the records it
+// is set on, so Dropped counts all of them
+type Sampler struct {
+	// dropped is accessed atomically, keep it first for 64-bit alignment
+	dropped [FATAL_LEVEL + 1]uint64
+
+	config SamplerConfig
+
+	mux    sync.Mutex
+	start  time.Time
+	counts map[sampleKey]int
+}
+
+func NewSampler(config SamplerConfig) *Sampler {
+	if config.Interval <= 0 {
+		config.Interval = defaultSampleInterval
+	}
+
+	if config.SampleRate == (SampleRate{}) {
+		config.SampleRate = defaultSampleRate
+	}
+
+	levels := map[LoggingLevel]SampleRate{
+		ERROR_LEVEL: NoSampling,
+		FATAL_LEVEL: NoSampling,
+	}
+	for level, rate := range config.Levels {
+		levels[level] = rate
+	}
+	config.Levels = levels
+
+	return &Sampler{
+		config: config,
+		counts: map[sampleKey]int{},
+	}
+}
+
+func (s *Sampler) rate(level LoggingLevel) SampleRate {
+	if rate, ok := s.config.Levels[level]; ok {
+		return rate
+	}
+
+	return s.config.SampleRate
+}
+
+// allow reports whether record is logged, the records with a format are
+// counted by their format so the arguments do not matter
+func (s *Sampler) allow(record *LogRecord) bool {
+	rate := s.rate(record.logLevel)
+	if rate.First < 0 {
+		return true
+	}
+
+	key := sampleKey{
+		level:   record.logLevel,
+		module:  record.module,
+		message: record.format,
+	}
+	if key.message == "" {
+		key.message = record.message()
+	}
+
+	now := time.Now()
+
+	s.mux.Lock()
+	if now.Sub(s.start) >= s.config.Interval {
+		s.start = now
+		s.counts = map[sampleKey]int{}
+	}
+
+	s.counts[key]++
+	n := s.counts[key]
+	s.mux.Unlock()
+
+	if n <= rate.First || (rate.Thereafter > 0 && (n-rate.First)%rate.Thereafter == 0) {
+		return true
+	}
+
+	if record.logLevel >= TRACE_LEVEL && record.logLevel <= FATAL_LEVEL {
+		atomic.AddUint64(&s.dropped[record.logLevel], 1)
+	}
+
+	return false
+}
+
+// Dropped returns the number of records sampled out
+func (s *Sampler) Dropped() uint64 {
+	var dropped uint64
+	for level := TRACE_LEVEL; level <= FATAL_LEVEL; level++ {
+		dropped += atomic.LoadUint64(&s.dropped[level])
+	}
+
+	return dropped
+}
+
+// DroppedLevel returns the number of records at level sampled out
+func (s *Sampler) DroppedLevel(level LoggingLevel) uint64 {
+	if level < TRACE_LEVEL || level > FATAL_LEVEL {
+		return 0
+	}
+
+	return atomic.LoadUint64(&s.dropped[level])
+}
+
+// SetSampler samples the records of the logging, the records which have
+// their own sampler use it instead. A nil sampler turns the sampling off
+func (l *logging) SetSampler(s *Sampler) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	l.sampler = s
+}
+
+// Sample returns a child record whose records are sampled by s, e.g. the
+// record of a module logging in a hot loop
+func (l *LogRecord) Sample(s *Sampler) *LogRecord {
+	child := *l
+	child.sampler = s
+	return &child
+}
+
+// sampled reports whether the record is dropped by its sampler or by the
+// sampler of the logging
+func (l *logging) sampled(record *LogRecord) bool {
+	s := record.sampler
+	if s == nil {
+		l.mux.Lock()
+		s = l.sampler
+		l.mux.Unlock()
+	}
+
+	return s != nil && !s.allow(record)
+}
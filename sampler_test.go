@@ -0,0 +1,78 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSampler(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logging := NewLogging("test", TRACE_LEVEL, 4)
+	logging.SetOutPut(buf)
+
+	sampler := NewSampler(SamplerConfig{
+		Interval:   time.Hour,
+		SampleRate: SampleRate{First: 2, Thereafter: 3},
+		Levels: map[LoggingLevel]SampleRate{
+			DEBUG_LEVEL: {First: 1},
+		},
+	})
+	logging.SetSampler(sampler)
+
+	for i := 0; i < 10; i++ {
+		logging.Infof("request %d", i)
+		logging.Debug("debug")
+		logging.Error("failed")
+	}
+
+	cases := []struct {
+		message  string
+		expected int
+	}{
+		// the first 2, then the 5th and the 8th
+		{"request", 4},
+		{"debug", 1},
+		{"failed", 10},
+	}
+
+	for _, c := range cases {
+		if n := strings.Count(buf.String(), c.message); n != c.expected {
+			t.Errorf("expected %d %s records, got %d", c.expected, c.message, n)
+		}
+	}
+
+	if sampler.Dropped() != 15 || sampler.DroppedLevel(INFO_LEVEL) != 6 || sampler.DroppedLevel(DEBUG_LEVEL) != 9 {
+		t.Errorf("unexpected dropped records %d %d %d", sampler.Dropped(), sampler.DroppedLevel(INFO_LEVEL), sampler.DroppedLevel(DEBUG_LEVEL))
+	}
+}
+
+func TestSampleRecord(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logging := NewLogging("test", INFO_LEVEL, 4)
+	logging.SetOutPut(buf)
+
+	sampler := NewSampler(SamplerConfig{
+		Interval:   20 * time.Millisecond,
+		SampleRate: SampleRate{First: 1},
+	})
+	hot := logging.Module("hot").Sample(sampler)
+
+	hot.Info("loop")
+	hot.Info("loop")
+	logging.Info("loop")
+
+	time.Sleep(30 * time.Millisecond)
+	hot.Info("loop")
+
+	if n := strings.Count(buf.String(), "loop"); n != 3 {
+		t.Errorf("expected 3 records, got %d", n)
+	}
+
+	if sampler.Dropped() != 1 {
+		t.Errorf("expected 1 dropped record, got %d", sampler.Dropped())
+	}
+}
@@ -0,0 +1,356 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Sink is an extra output of a logging, it only receives the records at or
+// above Level and formats them with Formatter, a nil Formatter means the
+// formatter of the logging is used. With Dedup the identical records are
+// collapsed before they reach Output
+type Sink struct {
+	Output    OutPut
+	Level     LoggingLevel
+	Formatter Formatter
+	Dedup     *DedupConfig
+}
+
+type sink struct {
+	mux sync.Mutex
+	Sink
+	dedup *deduper
+
+	// refs counts the batches holding the sink, once it is retired its
+	// output is closed when the last of them is written. Both are accessed
+	// atomically
+	refs      int32
+	retired   int32
+	closeOnce sync.Once
+}
+
+// acquire keeps the output of s open until release is called, it fails
+// once s is retired
+func (s *sink) acquire() bool {
+	atomic.AddInt32(&s.refs, 1)
+	if atomic.LoadInt32(&s.retired) != 0 {
+		s.release()
+		return false
+	}
+
+	return true
+}
+
+func (s *sink) release() {
+	if atomic.AddInt32(&s.refs, -1) == 0 && atomic.LoadInt32(&s.retired) != 0 {
+		s.close()
+	}
+}
+
+// retire stops the sink, the summaries of its pending repetitions are
+// written and its output is closed once the records already dispatched to it
+// are written
+func (s *sink) retire() {
+	if s.dedup != nil {
+		s.dedup.flush()
+	}
+
+	atomic.StoreInt32(&s.retired, 1)
+	if atomic.LoadInt32(&s.refs) == 0 {
+		s.close()
+	}
+}
+
+func (s *sink) close() {
+	s.closeOnce.Do(func() {
+		s.mux.Lock()
+		defer s.mux.Unlock()
+
+		closeOutput(s.Output)
+	})
+}
+
+func (s *sink) write(level LoggingLevel, buf *bytes.Buffer) (err error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("sink panic: %v", r)
+		}
+	}()
+
+	_, err = writeLevel(s.Output, level, buf.Bytes())
+	return err
+}
+
+func (l *logging) newSink(s Sink) *sink {
+	ns := &sink{Sink: s}
+	if s.Dedup != nil {
+		ns.dedup = newDeduper(l, ns, *s.Dedup)
+	}
+
+	return ns
+}
+
+// AddSink adds an output which receives the records of the logging in
+// addition to the default output
+func (l *logging) AddSink(s Sink) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	l.sinks = append(l.sinks, l.newSink(s))
+}
+
+// setSinks replaces the sinks, the old ones are returned so they can be
+// retired
+func (l *logging) setSinks(sinks []Sink) []*sink {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	return l.replaceSinks(sinks)
+}
+
+// replaceSinks is setSinks for the callers holding l.mux
+func (l *logging) replaceSinks(sinks []Sink) []*sink {
+	old := l.sinks
+	l.sinks = make([]*sink, 0, len(sinks))
+	for _, s := range sinks {
+		l.sinks = append(l.sinks, l.newSink(s))
+	}
+
+	return old
+}
+
+// SetErrorHandler sets the function which is called when the output or a
+// sink fails, by default the error is printed to stderr
+func (l *logging) SetErrorHandler(handler func(err error)) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	l.errorHandler = handler
+}
+
+func (l *logging) handleError(err error) {
+	l.mux.Lock()
+	handler := l.errorHandler
+	l.mux.Unlock()
+
+	if handler == nil {
+		fmt.Fprintf(os.Stderr, "log: %v\n", err)
+		return
+	}
+
+	handler(err)
+}
+
+const (
+	// the key of the buffer formatted by the formatter of the logging, it
+	// is shared by the default output and the sinks without a formatter
+	sharedBuffer = -1
+	// the key of the buffers which must not be shared
+	unsharedBuffer = -2
+)
+
+// batchWrite is a buffer to write to a sink, a nil sink is the default
+// output
+type batchWrite struct {
+	sink  *sink
+	level LoggingLevel
+	buf   *bytes.Buffer
+}
+
+// batchTarget is an output a record is written to, a nil sink is the
+// default output
+type batchTarget struct {
+	sink      *sink
+	key       int
+	formatter Formatter
+}
+
+// batch holds the formatted buffers of one record, it is written either
+// directly or by the async writer
+type batch struct {
+	level  LoggingLevel
+	writes []batchWrite
+	// every distinct buffer, they are put back into the pool once written
+	buffers []*bytes.Buffer
+	// the key of every buffer in buffers, sharedBuffer or the index of the
+	// sink with its own formatter
+	keys []int
+
+	// the dedup key of the record, computed on first use
+	key   dedupKey
+	keyed bool
+
+	// the hooks fired once the batch is written
+	hooks []*hook
+	entry *Entry
+}
+
+// format returns the buffer of record formatted by formatter, the buffer of
+// key is only formatted once
+func (b *batch) format(key int, formatter Formatter, record *LogRecord) *bytes.Buffer {
+	for i, k := range b.keys {
+		if k == key {
+			return b.buffers[i]
+		}
+	}
+
+	buf := formatter(record)
+	b.buffers = append(b.buffers, buf)
+	b.keys = append(b.keys, key)
+
+	return buf
+}
+
+// add formats record with formatter and writes it to s, the buffers of the
+// same key are shared. Nothing is written to a retired sink
+func (b *batch) add(s *sink, key int, formatter Formatter, record *LogRecord) {
+	if s != nil && !s.acquire() {
+		return
+	}
+
+	b.writes = append(b.writes, batchWrite{
+		sink:  s,
+		level: record.logLevel,
+		buf:   b.format(key, formatter, record),
+	})
+}
+
+// addSummary writes a dedup summary to s, it is not shared since it is
+// not the record of the batch
+func (b *batch) addSummary(s *sink, formatter Formatter, summary *LogRecord) {
+	if s != nil && !s.acquire() {
+		return
+	}
+
+	buf := formatter(summary)
+	b.buffers = append(b.buffers, buf)
+	b.keys = append(b.keys, unsharedBuffer)
+	b.writes = append(b.writes, batchWrite{sink: s, level: summary.logLevel, buf: buf})
+}
+
+// dispatch formats record once for the default output and the sinks without
+// a formatter, and once for every sink with its own formatter, then writes
+// it to the default output and to the sinks accepting its level. It must be
+// called at the same depth as formatters used to be called, because the
+// caller of the record is resolved here, unless it is already known.
+func (l *logging) dispatch(record *LogRecord) {
+	if l.sampled(record) {
+		return
+	}
+
+	// the hooks and every formatter see the same time
+	if record.time.IsZero() {
+		record.time = time.Now()
+	}
+
+	l.mux.Lock()
+	enableCaller := l.enableCaller
+	callerOptions := l.callerOptions
+	withStack := record.withStack || (l.stackEnabled && record.logLevel >= l.stackLevel)
+	formatter := l.Formater
+	output := l.output
+	sinks := l.sinks
+	async := l.async
+	dedup := l.dedup
+	hooks := l.hooks
+	l.mux.Unlock()
+
+	if !enableCaller {
+		record.enableCaller = false
+	}
+
+	if record.enableCaller {
+		resolveCaller(record, &callerOptions, record.callerLevel)
+	}
+
+	b := &batch{level: record.logLevel}
+
+	before := hooksOf(hooks, HOOK_BEFORE_WRITE, record.logLevel)
+	b.hooks = hooksOf(hooks, HOOK_AFTER_WRITE, record.logLevel)
+	if len(before) != 0 || len(b.hooks) != 0 {
+		b.entry = newEntry(record)
+		l.fireHooks(before, b.entry)
+	}
+
+	// the outputs the record is written to, they are collected first so
+	// the stack is only captured when the record is written somewhere
+	var targetsBuf [4]batchTarget
+	targets := targetsBuf[:0]
+
+	// a nil output means the records only go to the sinks
+	if output != nil && b.deduplicate(dedup, nil, formatter, record) {
+		targets = append(targets, batchTarget{nil, sharedBuffer, formatter})
+	}
+
+	for i, s := range sinks {
+		if record.logLevel < s.Level {
+			continue
+		}
+
+		key, sinkFormatter := sharedBuffer, formatter
+		if s.Formatter != nil {
+			key, sinkFormatter = i, s.Formatter
+		}
+
+		if b.deduplicate(s.dedup, s, sinkFormatter, record) {
+			targets = append(targets, batchTarget{s, key, sinkFormatter})
+		}
+	}
+
+	if len(targets) != 0 && withStack && record.stack == nil {
+		record.stack = captureStack()
+	}
+
+	for _, t := range targets {
+		b.add(t.sink, t.key, t.formatter, record)
+	}
+
+	if len(b.writes) == 0 {
+		l.fireHooks(b.hooks, b.entry)
+		return
+	}
+
+	if async != nil {
+		async.enqueue(b)
+		return
+	}
+
+	l.writeBatch(b)
+}
+
+func (l *logging) writeBatch(b *batch) {
+	for _, w := range b.writes {
+		if w.sink == nil {
+			l.write(w.level, w.buf)
+			continue
+		}
+
+		if err := w.sink.write(w.level, w.buf); err != nil {
+			l.handleError(err)
+		}
+	}
+
+	l.fireHooks(b.hooks, b.entry)
+	l.releaseBatch(b)
+}
+
+// releaseBatch puts the buffers of b back into the pool and releases its
+// sinks, it is called once b is written or dropped
+func (l *logging) releaseBatch(b *batch) {
+	for _, buf := range b.buffers {
+		l.pool.Put(buf)
+	}
+
+	for _, w := range b.writes {
+		if w.sink != nil {
+			w.sink.release()
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type failingOutput struct{}
+
+func (f *failingOutput) Write(p []byte) (int, error) {
+	return 0, errors.New("sink is broken")
+}
+
+func (f *failingOutput) Rotate() error {
+	return nil
+}
+
+func TestSinkLevel(t *testing.T) {
+	buf := &bytes.Buffer{}
+	warn := &BufferOutput{}
+	json := &BufferOutput{}
+
+	logging := NewLogging("test", DEBUG_LEVEL, 4)
+	logging.SetOutPut(buf)
+	logging.AddSink(Sink{Output: warn, Level: WARN_LEVEL})
+	logging.AddSink(Sink{Output: json, Level: DEBUG_LEVEL, Formatter: JSONFormatter(JSONFormatterOptions{})})
+
+	logging.Debug("debug message")
+	logging.Warn("warn message")
+
+	if !strings.Contains(buf.String(), "debug message") || !strings.Contains(buf.String(), "warn message") {
+		t.Errorf("unexpected default output %q", buf.String())
+	}
+
+	if strings.Contains(warn.String(), "debug message") || !strings.Contains(warn.String(), "Warn msg: warn message") {
+		t.Errorf("unexpected warn sink output %q", warn.String())
+	}
+
+	if strings.Count(json.String(), "{") != 2 || !strings.Contains(json.String(), `"msg":"warn message"`) {
+		t.Errorf("unexpected json sink output %q", json.String())
+	}
+}
+
+func TestSinkFormatOnce(t *testing.T) {
+	calls := 0
+	formatter := func(logRecord *LogRecord) *bytes.Buffer {
+		calls++
+		return DefaultFormater(logRecord)
+	}
+
+	first := &BufferOutput{}
+	second := &BufferOutput{}
+	json := &BufferOutput{}
+
+	logging := NewLoggingWithFormater(INFO_LEVEL, 4, formatter)
+	logging.SetOutPut(&bytes.Buffer{})
+	logging.AddSink(Sink{Output: first, Level: INFO_LEVEL})
+	logging.AddSink(Sink{Output: second, Level: INFO_LEVEL})
+	logging.AddSink(Sink{Output: json, Level: INFO_LEVEL, Formatter: func(logRecord *LogRecord) *bytes.Buffer {
+		calls++
+		return JSONFormatter(JSONFormatterOptions{})(logRecord)
+	}})
+
+	logging.Info("Test Message")
+
+	if calls != 2 {
+		t.Errorf("expected the record to be formatted twice, got %d", calls)
+	}
+
+	if first.String() != second.String() || !strings.Contains(first.String(), "Test Message") {
+		t.Errorf("unexpected sink output %q %q", first.String(), second.String())
+	}
+
+	if !strings.Contains(json.String(), `"msg":"Test Message"`) {
+		t.Errorf("unexpected json sink output %q", json.String())
+	}
+}
+
+func TestFailingSink(t *testing.T) {
+	buf := &bytes.Buffer{}
+	output := &BufferOutput{}
+
+	var errs []error
+	logging := NewLogging("test", INFO_LEVEL, 4)
+	logging.SetOutPut(buf)
+	logging.SetErrorHandler(func(err error) {
+		errs = append(errs, err)
+	})
+	logging.AddSink(Sink{Output: &failingOutput{}, Level: INFO_LEVEL})
+	logging.AddSink(Sink{Output: output, Level: INFO_LEVEL})
+
+	logging.Info("Test Message")
+
+	if len(errs) != 1 {
+		t.Errorf("expected one error, got %v", errs)
+	}
+
+	if !strings.Contains(buf.String(), "Test Message") || !strings.Contains(output.String(), "Test Message") {
+		t.Error("a failing sink should not block the others")
+	}
+}
+
+func TestFailingOutput(t *testing.T) {
+	output := &BufferOutput{}
+
+	var errs []error
+	logging := NewLogging("test", INFO_LEVEL, 4)
+	logging.SetOutPut(&failingOutput{})
+	logging.SetErrorHandler(func(err error) {
+		errs = append(errs, err)
+	})
+	logging.AddSink(Sink{Output: output, Level: INFO_LEVEL})
+
+	logging.Info("Test Message")
+
+	if len(errs) != 1 {
+		t.Errorf("expected one error, got %v", errs)
+	}
+
+	if !strings.Contains(output.String(), "Test Message") {
+		t.Error("a failing output should not block the sinks")
+	}
+}
@@ -0,0 +1,141 @@
+//go:build go1.21
+// +build go1.21
+
+package log
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+)
+
+var _ slog.Handler = &SlogHandler{}
+
+// SlogHandler is a slog.Handler writing the slog records through a logging,
+// so they share its level, formatter, outputs and rotation
+type SlogHandler struct {
+	logger *logging
+	module string
+	// attrs added by WithAttrs, the group prefix is already applied
+	fields []Field
+	// prefix of the keys, it is built from the names passed to WithGroup
+	prefix string
+}
+
+func NewSlogHandler(l *logging, module string) *SlogHandler {
+	return &SlogHandler{
+		logger: l,
+		module: module,
+	}
+}
+
+// slogLevel maps a slog level onto a LoggingLevel
+func slogLevel(level slog.Level) LoggingLevel {
+	switch {
+	case level < slog.LevelDebug:
+		return TRACE_LEVEL
+	case level < slog.LevelInfo:
+		return DEBUG_LEVEL
+	case level < slog.LevelWarn:
+		return INFO_LEVEL
+	case level < slog.LevelError:
+		return WARN_LEVEL
+	case level < slog.LevelError+4:
+		return ERROR_LEVEL
+	default:
+		return FATAL_LEVEL
+	}
+}
+
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.logger.levelOf(h.module) <= slogLevel(level)
+}
+
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	fields := make([]Field, 0, len(h.fields)+r.NumAttrs())
+	fields = append(fields, h.fields...)
+
+	r.Attrs(func(attr slog.Attr) bool {
+		fields = appendSlogAttr(fields, h.prefix, attr)
+		return true
+	})
+
+	record := &LogRecord{
+		logLevel:     slogLevel(r.Level),
+		args:         []interface{}{r.Message},
+		module:       h.module,
+		enableCaller: h.logger.enableCaller,
+		logger:       h.logger,
+		fields:       mergeFields(contextFields(ctx), fields),
+	}
+
+	if !r.Time.IsZero() {
+		record.time = r.Time
+	}
+
+	// the caller is taken from the slog record instead of the stack, a
+	// record without a PC has no caller
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		record.file = frame.File
+		record.line = frame.Line
+		record.function = frame.Function
+		record.callerResolved = true
+	} else {
+		record.enableCaller = false
+	}
+
+	h.logger.dispatch(record)
+	return nil
+}
+
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	child := *h
+	child.fields = make([]Field, len(h.fields), len(h.fields)+len(attrs))
+	copy(child.fields, h.fields)
+
+	for _, attr := range attrs {
+		child.fields = appendSlogAttr(child.fields, h.prefix, attr)
+	}
+
+	return &child
+}
+
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	child := *h
+	child.prefix = h.prefix + name + "."
+	return &child
+}
+
+// appendSlogAttr appends attr to fields, the attrs of a group are flattened
+// into keys like "group.key"
+func appendSlogAttr(fields []Field, prefix string, attr slog.Attr) []Field {
+	attr.Value = attr.Value.Resolve()
+
+	if attr.Equal(slog.Attr{}) {
+		return fields
+	}
+
+	if attr.Value.Kind() == slog.KindGroup {
+		// a group without a name is inlined
+		if attr.Key != "" {
+			prefix = prefix + attr.Key + "."
+		}
+
+		for _, groupAttr := range attr.Value.Group() {
+			fields = appendSlogAttr(fields, prefix, groupAttr)
+		}
+
+		return fields
+	}
+
+	return append(fields, Field{Key: prefix + attr.Key, Value: attr.Value.Any()})
+}
@@ -0,0 +1,97 @@
+//go:build go1.21
+// +build go1.21
+
+package log
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlogHandler(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logging := NewLogging("test", INFO_LEVEL, 4)
+	logging.SetOutPut(buf)
+
+	slogger := slog.New(NewSlogHandler(logging, "slog"))
+	slogger.Warn("Test Message", "user", 42, slog.Group("request", "id", "abc"))
+
+	s := buf.String()
+	for _, expected := range []string{
+		"[ slog ] ",
+		"slog_test.go:",
+		" Warn msg: Test Message user=42 request.id=abc\n",
+	} {
+		if !strings.Contains(s, expected) {
+			t.Errorf("expected %q in %q", expected, s)
+		}
+	}
+
+	buf.Reset()
+	slogger.Debug("Test Message")
+	if buf.Len() != 0 {
+		t.Error("test level failed")
+	}
+}
+
+func TestSlogHandlerWithAttrs(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logging := NewLogging("test", TRACE_LEVEL, 4)
+	logging.SetOutPut(buf)
+
+	slogger := slog.New(NewSlogHandler(logging, ""))
+	child := slogger.With("service", "api").WithGroup("http").With("method", "GET")
+	child.Info("Test Message", "status", 200)
+
+	if !strings.Contains(buf.String(), " Info msg: Test Message service=api http.method=GET http.status=200\n") {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+
+	buf.Reset()
+	slogger.Info("Test Message")
+	if !strings.Contains(buf.String(), " Info msg: Test Message\n") {
+		t.Errorf("parent handler is modified by child: %q", buf.String())
+	}
+}
+
+func TestSlogLevel(t *testing.T) {
+	cases := map[slog.Level]LoggingLevel{
+		slog.LevelDebug - 4: TRACE_LEVEL,
+		slog.LevelDebug:     DEBUG_LEVEL,
+		slog.LevelInfo:      INFO_LEVEL,
+		slog.LevelInfo + 2:  INFO_LEVEL,
+		slog.LevelWarn:      WARN_LEVEL,
+		slog.LevelError:     ERROR_LEVEL,
+		slog.LevelError + 4: FATAL_LEVEL,
+	}
+
+	for level, expected := range cases {
+		if slogLevel(level) != expected {
+			t.Errorf("expected %v for %v, got %v", expected, level, slogLevel(level))
+		}
+	}
+}
+
+func TestSlogHandlerRecord(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logging := NewLogging("test", INFO_LEVEL, 4)
+	logging.SetOutPut(buf)
+
+	// a record built without a PC has no caller
+	r := slog.NewRecord(time.Date(2020, 1, 2, 3, 4, 5, 6e6, time.Local), slog.LevelInfo, "Test Message", 0)
+	if err := NewSlogHandler(logging, "").Handle(context.Background(), r); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if buf.String() != "2020-01-02 03:04:05,6 Info msg: Test Message\n" {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+}
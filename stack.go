@@ -0,0 +1,81 @@
+package log
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+)
+
+// maxStackDepth is the max number of frames captured in a stack trace
+const maxStackDepth = 64
+
+// EnableStack captures a stack trace for the records at or above level
+func (l *logging) EnableStack(level LoggingLevel) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	l.stackEnabled = true
+	l.stackLevel = level
+}
+
+// DisableStack stops capturing the stack traces, except for the records
+// created with WithStack
+func (l *logging) DisableStack() {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	l.stackEnabled = false
+}
+
+// WithStack returns a record carrying a stack trace whatever its level
+func (l *logging) WithStack() *LogRecord {
+	return &LogRecord{
+		callerLevel:  l.callerLevel,
+		enableCaller: l.enableCaller,
+		logger:       l,
+		withStack:    true,
+	}
+}
+
+// WithStack returns a child record whose records carry a stack trace
+// whatever their level
+func (l *LogRecord) WithStack() *LogRecord {
+	child := *l
+	child.withStack = true
+	return &child
+}
+
+// captureStack returns the stack of the caller, the frames of this package
+// and of the logging helpers are filtered out
+func captureStack() []runtime.Frame {
+	var pcs [maxStackDepth]uintptr
+	n := runtime.Callers(2, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	h := helpersValue.Load().(*helpers)
+
+	stack := make([]runtime.Frame, 0, n)
+	for {
+		frame, more := frames.Next()
+		if !h.isHelper(&frame) {
+			stack = append(stack, frame)
+		}
+
+		if !more {
+			return stack
+		}
+	}
+}
+
+// writeStack writes the stack as a block of lines after a record, like
+// the stack traces of a panic
+func writeStack(buf *bytes.Buffer, stack []runtime.Frame) {
+	for i := range stack {
+		buf.WriteString("\t")
+		buf.WriteString(stack[i].Function)
+		buf.WriteString("\n\t\t")
+		buf.WriteString(stack[i].File)
+		buf.WriteString(":")
+		buf.WriteString(strconv.Itoa(stack[i].Line))
+		buf.WriteString("\n")
+	}
+}
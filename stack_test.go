@@ -0,0 +1,64 @@
+package log_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/wh8199/log"
+)
+
+func TestEnableStack(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logging := log.NewLogging("test", log.INFO_LEVEL, 4)
+	logging.SetOutPut(buf)
+	logging.EnableStack(log.ERROR_LEVEL)
+	logging.Warn("no stack")
+	logging.Error("failed")
+
+	lines := strings.Split(buf.String(), "\n")
+	if len(lines) < 4 || strings.HasPrefix(lines[1], "\t") {
+		t.Errorf("unexpected stack in %q", buf.String())
+		return
+	}
+
+	if lines[2] != "\t"+packagePath+"_test.TestEnableStack" || !strings.Contains(lines[3], "stack_test.go:") {
+		t.Errorf("expected the test function at the top of %q", buf.String())
+	}
+
+	if strings.Contains(buf.String(), packagePath+".(*logging)") {
+		t.Errorf("unexpected frame of the logger in %q", buf.String())
+	}
+
+	buf.Reset()
+	logging.DisableStack()
+	logging.Error("failed")
+	if strings.Contains(buf.String(), "\t") {
+		t.Errorf("unexpected stack in %q", buf.String())
+	}
+}
+
+func TestWithStack(t *testing.T) {
+	cases := []struct {
+		formatter log.Formatter
+		expected  string
+	}{
+		{log.DefaultFormater, "\n\t" + packagePath + "_test.TestWithStack\n\t\t"},
+		{log.JSONFormatter(log.JSONFormatterOptions{}), `"stack":[{"func":"` + packagePath + `_test.TestWithStack","file":"`},
+		{log.LogfmtFormatter, ` stack="` + packagePath + `_test.TestWithStack `},
+	}
+
+	for _, c := range cases {
+		buf := &bytes.Buffer{}
+
+		logging := log.NewLogging("test", log.INFO_LEVEL, 4)
+		logging.SetOutPut(buf)
+		logging.SetFormatter(c.formatter)
+		logging.Module("db").WithStack().Info("hello")
+
+		if !strings.Contains(buf.String(), c.expected) {
+			t.Errorf("expected %q in %q", c.expected, buf.String())
+		}
+	}
+}
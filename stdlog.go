@@ -0,0 +1,62 @@
+package log
+
+import (
+	stdlog "log"
+	"strings"
+)
+
+// stdWriter receives the output of a stdlib *log.Logger, every write is one
+// record
+type stdWriter struct {
+	logger *logging
+	level  LoggingLevel
+	module string
+}
+
+func (w *stdWriter) Write(p []byte) (int, error) {
+	if w.logger.levelOf(w.module) > w.level {
+		return len(p), nil
+	}
+
+	record := &LogRecord{
+		logLevel:     w.level,
+		args:         []interface{}{strings.TrimSuffix(string(p), "\n")},
+		module:       w.module,
+		enableCaller: w.logger.enableCaller,
+		logger:       w.logger,
+	}
+
+	// the stdlib log package is a logging helper
+	frame, _ := callerFrame()
+	record.file = frame.File
+	record.line = frame.Line
+	record.function = frame.Function
+	record.callerResolved = true
+
+	w.logger.dispatch(record)
+	return len(p), nil
+}
+
+// NewStdLogger returns a stdlib *log.Logger which writes into l at level,
+// e.g. for http.Server.ErrorLog
+func NewStdLogger(l *logging, level LoggingLevel, module string) *stdlog.Logger {
+	return stdlog.New(&stdWriter{logger: l, level: level, module: module}, "", 0)
+}
+
+// RedirectStdLog makes the global stdlib logger write into l at level, the
+// returned function restores the previous output, prefix and flags
+func RedirectStdLog(l *logging, level LoggingLevel, module string) func() {
+	flags := stdlog.Flags()
+	prefix := stdlog.Prefix()
+	output := stdlog.Writer()
+
+	stdlog.SetFlags(0)
+	stdlog.SetPrefix("")
+	stdlog.SetOutput(&stdWriter{logger: l, level: level, module: module})
+
+	return func() {
+		stdlog.SetFlags(flags)
+		stdlog.SetPrefix(prefix)
+		stdlog.SetOutput(output)
+	}
+}
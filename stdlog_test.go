@@ -0,0 +1,63 @@
+package log_test
+
+import (
+	"bytes"
+	stdlog "log"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/wh8199/log"
+)
+
+// the bridge is tested from outside of the package, so the call sites are
+// ordinary callers and not frames of the logger
+
+func TestStdLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logging := log.NewLogging("test", log.INFO_LEVEL, 4)
+	logging.SetOutPut(buf)
+
+	std := log.NewStdLogger(logging, log.WARN_LEVEL, "http")
+	_, file, line, _ := runtime.Caller(0)
+	std.Printf("Test %s", "Message")
+
+	s := buf.String()
+	for _, expected := range []string{
+		"[ http ] ",
+		file + ":" + strconv.Itoa(line+1) + " Warn msg: Test Message\n",
+	} {
+		if !strings.Contains(s, expected) {
+			t.Errorf("expected %q in %q", expected, s)
+		}
+	}
+
+	buf.Reset()
+	log.NewStdLogger(logging, log.DEBUG_LEVEL, "http").Print("Test Message")
+	if buf.Len() != 0 {
+		t.Error("test level failed")
+	}
+}
+
+func TestRedirectStdLog(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logging := log.NewLogging("test", log.INFO_LEVEL, 4)
+	logging.SetOutPut(buf)
+
+	output := stdlog.Writer()
+	restore := log.RedirectStdLog(logging, log.INFO_LEVEL, "std")
+	_, file, line, _ := runtime.Caller(0)
+	stdlog.Println("Test Message")
+	restore()
+
+	if !strings.Contains(buf.String(), file+":"+strconv.Itoa(line+1)+" Info msg: Test Message\n") {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+
+	if stdlog.Writer() != output {
+		t.Error("stdlib logger is not restored")
+	}
+}
@@ -0,0 +1,281 @@
+package log
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type SyslogFormat int
+
+const (
+	RFC5424 SyslogFormat = iota
+	RFC3164
+)
+
+// syslog facilities
+const (
+	LOG_KERN = iota
+	LOG_USER
+	LOG_MAIL
+	LOG_DAEMON
+	LOG_AUTH
+	LOG_SYSLOG
+	LOG_LPR
+	LOG_NEWS
+	LOG_UUCP
+	LOG_CRON
+	LOG_AUTHPRIV
+	LOG_FTP
+	_
+	_
+	_
+	_
+	LOG_LOCAL0
+	LOG_LOCAL1
+	LOG_LOCAL2
+	LOG_LOCAL3
+	LOG_LOCAL4
+	LOG_LOCAL5
+	LOG_LOCAL6
+	LOG_LOCAL7
+)
+
+type SyslogConfig struct {
+	// "unixgram", "unix", "udp" or "tcp", an empty Network and Address
+	// connect to the local syslog daemon
+	Network string `json:"network"`
+	Address string `json:"address"`
+	// LOG_USER if it is nil, a pointer so LOG_KERN, which is 0, can be set
+	Facility *int   `json:"facility"`
+	AppName  string `json:"appName"`
+	// os.Hostname() if it is not set
+	Hostname string       `json:"hostname"`
+	Format   SyslogFormat `json:"format"`
+	// TLS is used over tcp when TLSConfig is set
+	TLSConfig *tls.Config `json:"-"`
+}
+
+var _ OutPut = &SyslogOutput{}
+var _ LevelWriter = &SyslogOutput{}
+
+// SyslogOutput sends every record to a syslog daemon, stream transports use
+// octet-counted framing as described by RFC 6587
+type SyslogOutput struct {
+	SyslogConfig
+
+	mux  sync.Mutex
+	conn net.Conn
+	pid  string
+	// the value of Facility
+	facility int
+	buf      bytes.Buffer
+	// buf with the octet-counted framing of stream transports
+	frame bytes.Buffer
+}
+
+var localSyslogAddresses = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+func NewSyslogOutput(config SyslogConfig) (*SyslogOutput, error) {
+	facility := LOG_USER
+	if config.Facility != nil {
+		facility = *config.Facility
+	}
+
+	if facility < LOG_KERN || facility > LOG_LOCAL7 {
+		return nil, fmt.Errorf("invalid syslog facility %d", facility)
+	}
+
+	if config.Hostname == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			hostname = "-"
+		}
+		config.Hostname = hostname
+	}
+
+	if config.AppName == "" {
+		config.AppName = "-"
+	}
+
+	s := &SyslogOutput{
+		SyslogConfig: config,
+		pid:          strconv.Itoa(os.Getpid()),
+		facility:     facility,
+	}
+
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *SyslogOutput) connect() error {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+
+	if s.Network == "" && s.Address == "" {
+		for _, network := range []string{"unixgram", "unix"} {
+			for _, address := range localSyslogAddresses {
+				conn, err := net.Dial(network, address)
+				if err == nil {
+					s.conn = conn
+					return nil
+				}
+			}
+		}
+
+		return errors.New("local syslog daemon is unreachable")
+	}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+
+	if s.TLSConfig != nil && s.Network == "tcp" {
+		conn, err = tls.Dial(s.Network, s.Address, s.TLSConfig)
+	} else {
+		conn, err = net.Dial(s.Network, s.Address)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.conn = conn
+	return nil
+}
+
+// severity maps a LoggingLevel onto a syslog severity
+func severity(level LoggingLevel) int {
+	switch level {
+	case TRACE_LEVEL, DEBUG_LEVEL:
+		return 7
+	case INFO_LEVEL:
+		return 6
+	case WARN_LEVEL:
+		return 4
+	case ERROR_LEVEL:
+		return 3
+	case FATAL_LEVEL:
+		return 2
+	default:
+		return 5
+	}
+}
+
+func (s *SyslogOutput) isStream() bool {
+	switch s.conn.(type) {
+	case *net.UDPConn:
+		return false
+	case *net.UnixConn:
+		return s.conn.RemoteAddr().Network() == "unix"
+	default:
+		return true
+	}
+}
+
+// format writes the syslog message of p into s.buf
+func (s *SyslogOutput) format(level LoggingLevel, t time.Time, p []byte) {
+	p = bytes.TrimRight(p, "\n")
+
+	s.buf.Reset()
+	s.buf.WriteString("<")
+	s.buf.WriteString(strconv.Itoa(s.facility*8 + severity(level)))
+	s.buf.WriteString(">")
+
+	if s.Format == RFC3164 {
+		s.buf.WriteString(t.Format(time.Stamp))
+		s.buf.WriteString(" ")
+		s.buf.WriteString(s.Hostname)
+		s.buf.WriteString(" ")
+		s.buf.WriteString(s.AppName)
+		s.buf.WriteString("[")
+		s.buf.WriteString(s.pid)
+		s.buf.WriteString("]: ")
+	} else {
+		s.buf.WriteString("1 ")
+		s.buf.WriteString(t.Format("2006-01-02T15:04:05.000000Z07:00"))
+		s.buf.WriteString(" ")
+		s.buf.WriteString(s.Hostname)
+		s.buf.WriteString(" ")
+		s.buf.WriteString(s.AppName)
+		s.buf.WriteString(" ")
+		s.buf.WriteString(s.pid)
+		// no MSGID and no STRUCTURED-DATA
+		s.buf.WriteString(" - - ")
+	}
+
+	s.buf.Write(p)
+}
+
+func (s *SyslogOutput) send() error {
+	if s.conn == nil {
+		return errors.New("syslog is not connected")
+	}
+
+	if !s.isStream() {
+		_, err := s.conn.Write(s.buf.Bytes())
+		return err
+	}
+
+	s.frame.Reset()
+	s.frame.WriteString(strconv.Itoa(s.buf.Len()))
+	s.frame.WriteString(" ")
+	s.frame.Write(s.buf.Bytes())
+
+	_, err := s.conn.Write(s.frame.Bytes())
+	return err
+}
+
+// WriteLevel sends p with the severity of level, the connection is
+// re-established once if the write fails
+func (s *SyslogOutput) WriteLevel(level LoggingLevel, p []byte) (int, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.format(level, time.Now(), p)
+
+	if err := s.send(); err != nil {
+		if err := s.connect(); err != nil {
+			return 0, err
+		}
+
+		if err := s.send(); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// Write sends p with the severity of INFO_LEVEL
+func (s *SyslogOutput) Write(p []byte) (int, error) {
+	return s.WriteLevel(INFO_LEVEL, p)
+}
+
+func (s *SyslogOutput) Rotate() error {
+	return nil
+}
+
+func (s *SyslogOutput) Close() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
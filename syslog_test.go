@@ -0,0 +1,258 @@
+package log
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readFrame reads an octet-counted syslog frame
+func readFrame(r *bufio.Reader) (string, error) {
+	length, err := r.ReadString(' ')
+	if err != nil {
+		return "", err
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(length))
+	if err != nil {
+		return "", err
+	}
+
+	message := make([]byte, n)
+	if _, err := io.ReadFull(r, message); err != nil {
+		return "", err
+	}
+
+	return string(message), nil
+}
+
+func TestSyslogUDP(t *testing.T) {
+	local0, kern := LOG_LOCAL0, LOG_KERN
+
+	cases := []struct {
+		facility *int
+		// facility * 8 + error
+		priority string
+	}{
+		{&local0, "<131>"},
+		{&kern, "<3>"},
+		{nil, "<11>"},
+	}
+
+	for _, c := range cases {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		output, err := NewSyslogOutput(SyslogConfig{
+			Network:  "udp",
+			Address:  conn.LocalAddr().String(),
+			Facility: c.facility,
+			AppName:  "test",
+			Hostname: "host",
+		})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer output.Close()
+
+		logging := NewLogging("test", INFO_LEVEL, 4)
+		logging.SetOutPut(output)
+		logging.Error("Test Message")
+
+		buf := make([]byte, 1024)
+		conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		pattern := `^` + c.priority + `1 \S+ host test ` + strconv.Itoa(os.Getpid()) + ` - - .*Error msg: Test Message$`
+		if !regexp.MustCompile(pattern).Match(buf[:n]) {
+			t.Errorf("unexpected syslog message %q", buf[:n])
+		}
+	}
+}
+
+func testSyslogStream(t *testing.T, listener net.Listener, config SyslogConfig) {
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			received <- nil
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		messages := []string{}
+		for i := 0; i < 2; i++ {
+			message, err := readFrame(r)
+			if err != nil {
+				break
+			}
+			messages = append(messages, message)
+		}
+		received <- messages
+	}()
+
+	config.Format = RFC3164
+	config.AppName = "test"
+	config.Hostname = "host"
+	output, err := NewSyslogOutput(config)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer output.Close()
+
+	output.WriteLevel(WARN_LEVEL, []byte("first\n"))
+	output.WriteLevel(DEBUG_LEVEL, []byte("second with\nnewline\n"))
+
+	var messages []string
+	select {
+	case messages = <-received:
+	case <-time.After(time.Second * 5):
+		t.Error("syslog messages are not received")
+		return
+	}
+
+	if len(messages) != 2 {
+		t.Errorf("unexpected syslog messages %q", messages)
+		return
+	}
+
+	first := regexp.MustCompile(`^<12>\w{3} [ \d]\d \d\d:\d\d:\d\d host test\[\d+\]: first$`)
+	if !first.MatchString(messages[0]) {
+		t.Errorf("unexpected syslog message %q", messages[0])
+	}
+
+	if !strings.HasPrefix(messages[1], "<15>") || !strings.HasSuffix(messages[1], ": second with\nnewline") {
+		t.Errorf("unexpected syslog message %q", messages[1])
+	}
+}
+
+func TestSyslogTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer listener.Close()
+
+	testSyslogStream(t, listener, SyslogConfig{Network: "tcp", Address: listener.Addr().String()})
+}
+
+func TestSyslogTLS(t *testing.T) {
+	cert, pool, err := selfSignedCert()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer listener.Close()
+
+	testSyslogStream(t, listener, SyslogConfig{
+		Network:   "tcp",
+		Address:   listener.Addr().String(),
+		TLSConfig: &tls.Config{RootCAs: pool, ServerName: "127.0.0.1"},
+	})
+}
+
+func TestSyslogUnixgram(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	address := filepath.Join(dir, "syslog.sock")
+	conn, err := net.ListenPacket("unixgram", address)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer conn.Close()
+
+	output, err := NewSyslogOutput(SyslogConfig{Network: "unixgram", Address: address})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer output.Close()
+
+	output.Write([]byte("Test Message\n"))
+
+	buf := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// user * 8 + info, without app name
+	if !strings.HasPrefix(string(buf[:n]), "<14>1 ") || !strings.HasSuffix(string(buf[:n]), " - "+strconv.Itoa(os.Getpid())+" - - Test Message") {
+		t.Errorf("unexpected syslog message %q", buf[:n])
+	}
+}
+
+func selfSignedCert() (tls.Certificate, *x509.CertPool, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+
+	parsed, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(parsed)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool, nil
+}
@@ -18,14 +18,19 @@ type timeCache struct {
 }
 
 func CacheTime() string {
+	return formatCacheTime(time.Now())
+}
+
+// formatCacheTime formats t like CacheTime, the seconds are cached so
+// the records of the same second are formatted once
+func formatCacheTime(t time.Time) string {
 	var s string
-	t := time.Now()
 	nano := t.UnixNano()
 	now := nano / 1e9
 	value := lastTime.Load()
 	if value != nil {
 		last := value.(*timeCache)
-		if now <= last.t {
+		if now == last.t {
 			s = last.s
 		}
 	}
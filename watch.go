@@ -0,0 +1,85 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+)
+
+const defaultWatchInterval = 2 * time.Second
+
+// WatchConfig loads the config file at path like LoadConfig, then polls it
+// every interval and loads it again when its content changes. A content
+// which fails to load is reported once to the error handler of the global
+// logger, and the loggers keep their current config. The returned function
+// stops the watcher
+func WatchConfig(path string, interval time.Duration) (func(), error) {
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := LoadConfig(path); err != nil {
+		return nil, err
+	}
+
+	exit := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		// the last reported error, so a broken file is reported once
+		lastErr := ""
+		report := func(err error) {
+			if err.Error() != lastErr {
+				lastErr = err.Error()
+				logger.handleError(fmt.Errorf("reload config: %v", err))
+			}
+		}
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-exit:
+				return
+			}
+
+			current, err := ioutil.ReadFile(path)
+			if err != nil {
+				report(err)
+				continue
+			}
+
+			if bytes.Equal(current, data) {
+				continue
+			}
+			data = current
+
+			if err := LoadConfig(path); err != nil {
+				report(err)
+				continue
+			}
+			lastErr = ""
+		}
+	}()
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			close(exit)
+			<-done
+		})
+	}
+
+	return stop, nil
+}
@@ -0,0 +1,76 @@
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	defer Unregister("watch-test")
+
+	path := filepath.Join(dir, "log.json")
+	if err := ioutil.WriteFile(path, []byte(`{"loggers": {"watch-test": {"level": "info"}}}`), 0644); err != nil {
+		t.Error(err)
+		return
+	}
+
+	stop, err := WatchConfig(path, 10*time.Millisecond)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer stop()
+
+	l, ok := Lookup("watch-test")
+	if !ok || l.GetLevel() != INFO_LEVEL {
+		t.Error("the config is not loaded")
+		return
+	}
+
+	errs := make(chan error, 10)
+	logger.SetErrorHandler(func(err error) {
+		errs <- err
+	})
+	defer logger.SetErrorHandler(nil)
+
+	// an invalid config is reported and ignored
+	if err := ioutil.WriteFile(path, []byte(`{"loggers": {"watch-test": {"level": "verbose"}}}`), 0644); err != nil {
+		t.Error(err)
+		return
+	}
+
+	select {
+	case <-errs:
+	case <-time.After(5 * time.Second):
+		t.Error("the invalid config is not reported")
+		return
+	}
+
+	if l.GetLevel() != INFO_LEVEL {
+		t.Errorf("expected %s, got %s", INFO_LEVEL, l.GetLevel())
+		return
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`{"loggers": {"watch-test": {"level": "debug"}}}`), 0644); err != nil {
+		t.Error(err)
+		return
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for l.GetLevel() != DEBUG_LEVEL {
+		if time.Now().After(deadline) {
+			t.Errorf("expected %s, got %s", DEBUG_LEVEL, l.GetLevel())
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}